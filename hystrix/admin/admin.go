@@ -0,0 +1,362 @@
+// Package admin serves an HTTP API to inspect and control the circuits of a hystrix registry at runtime.
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+)
+
+// maxBodySize is the size in bytes beyond which request bodies are rejected.
+const maxBodySize = 1 << 16
+
+// Circuit is the JSON representation of a circuit.
+type Circuit struct {
+	Name         string `json:"name"`
+	CommandGroup string `json:"command_group"`
+	// State is "closed", "open" or "half-open".
+	State string `json:"state"`
+	// Settings are in the format of hystrix.CommandConfig, durations are in milliseconds.
+	Settings hystrix.CommandConfig `json:"settings"`
+	Counts   RollingCounts         `json:"rolling_counts"`
+	Pool     PoolUsage             `json:"pool"`
+}
+
+// RollingCounts are the events counted in the rolling window of a circuit.
+type RollingCounts struct {
+	Requests                uint64 `json:"requests"`
+	Errors                  uint64 `json:"errors"`
+	ErrorPercent            int    `json:"error_percent"`
+	Successes               uint64 `json:"successes"`
+	Failures                uint64 `json:"failures"`
+	Rejects                 uint64 `json:"rejects"`
+	ShortCircuits           uint64 `json:"short_circuits"`
+	Timeouts                uint64 `json:"timeouts"`
+	ContextCanceled         uint64 `json:"context_canceled"`
+	ContextDeadlineExceeded uint64 `json:"context_deadline_exceeded"`
+	FallbackSuccesses       uint64 `json:"fallback_successes"`
+	FallbackFailures        uint64 `json:"fallback_failures"`
+	FallbackRejections      uint64 `json:"fallback_rejections"`
+	Hedges                  uint64 `json:"hedges"`
+}
+
+// PoolUsage is the use of the executor pool of a circuit.
+type PoolUsage struct {
+	Active  int `json:"active"`
+	Waiting int `json:"waiting"`
+	// Limit is the number of commands the pool runs at the same time, which follows its adaptive limit if any.
+	Limit int `json:"limit"`
+}
+
+// Handler serves the admin API for the circuits of a registry:
+//
+//	GET  /circuits                      lists every circuit
+//	GET  /circuits/{name}               returns a single circuit
+//	POST /circuits/{name}/force-open    forces the circuit open
+//	POST /circuits/{name}/force-closed  forces the circuit closed
+//	POST /circuits/{name}/clear-force   lets the health checks decide again
+//	POST /circuits/{name}/reset         clears the rolling metrics
+//	POST /circuits/{name}/settings      changes the settings present in the JSON body
+//
+// Names are path escaped and POST requests respond with the circuit after the change. POST requests must have
+// the Content-Type application/json, even without a body, so that browsers cannot send them from other sites
+// without a preflight request. The handler allows anyone reaching it to take circuits down, so only mount it on an
+// internal or authenticated port.
+type Handler struct {
+	registry *hystrix.Registry
+}
+
+// NewHandler returns a Handler for the circuits of the default registry.
+func NewHandler() *Handler {
+	return NewRegistryHandler(hystrix.DefaultRegistry())
+}
+
+// NewRegistryHandler returns a Handler for the circuits of the given registry.
+func NewRegistryHandler(registry *hystrix.Registry) *Handler {
+	return &Handler{registry: registry}
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+func (h *Handler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(req.URL.EscapedPath(), "/"), "/")
+	if parts[0] != "circuits" {
+		writeError(rw, http.StatusNotFound, "not found")
+		return
+	}
+
+	if len(parts) == 1 {
+		if !allowMethod(rw, req, http.MethodGet) {
+			return
+		}
+		h.list(rw)
+		return
+	}
+
+	if len(parts) > 3 {
+		writeError(rw, http.StatusNotFound, "not found")
+		return
+	}
+	name, err := url.PathUnescape(parts[1])
+	if err != nil {
+		writeError(rw, http.StatusBadRequest, fmt.Sprintf("invalid circuit name: %v", err))
+		return
+	}
+	cb, ok := h.registry.Circuits()[name]
+	if !ok {
+		writeError(rw, http.StatusNotFound, fmt.Sprintf("unknown circuit %q", name))
+		return
+	}
+
+	if len(parts) == 2 {
+		if !allowMethod(rw, req, http.MethodGet) {
+			return
+		}
+		writeJSON(rw, http.StatusOK, h.circuit(cb))
+		return
+	}
+
+	action := parts[2]
+	switch action {
+	case "force-open", "force-closed", "clear-force", "reset", "settings":
+	default:
+		writeError(rw, http.StatusNotFound, fmt.Sprintf("unknown action %q", action))
+		return
+	}
+	if !allowMethod(rw, req, http.MethodPost) {
+		return
+	}
+	if mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(rw, http.StatusUnsupportedMediaType, "content type must be application/json")
+		return
+	}
+	req.Body = http.MaxBytesReader(rw, req.Body, maxBodySize)
+
+	switch action {
+	case "force-open":
+		cb.ForceOpen()
+	case "force-closed":
+		cb.ForceClosed()
+	case "clear-force":
+		cb.ClearForce()
+	case "reset":
+		cb.ResetMetrics()
+	case "settings":
+		if err := h.updateSettings(name, req.Body); err != nil {
+			writeError(rw, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	writeJSON(rw, http.StatusOK, h.circuit(cb))
+}
+
+func (h *Handler) list(rw http.ResponseWriter) {
+	circuits := h.registry.Circuits()
+	names := make([]string, 0, len(circuits))
+	for name := range circuits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]Circuit, 0, len(names))
+	for _, name := range names {
+		result = append(result, h.circuit(circuits[name]))
+	}
+	writeJSON(rw, http.StatusOK, result)
+}
+
+func (h *Handler) circuit(cb *hystrix.CircuitBreaker) Circuit {
+	var config hystrix.CommandConfig
+	if settings, ok := h.registry.GetCircuitSettings()[cb.Name]; ok {
+		config = commandConfig(settings)
+	}
+
+	return Circuit{
+		Name:         cb.Name,
+		CommandGroup: cb.CommandGroup,
+		State:        cb.State().String(),
+		Settings:     config,
+		Counts:       rollingCounts(cb, time.Now()),
+		Pool: PoolUsage{
+			Active:  cb.ActiveCount(),
+			Waiting: cb.WaitingCount(),
+			Limit:   cb.ConcurrencyLimit(),
+		},
+	}
+}
+
+func rollingCounts(cb *hystrix.CircuitBreaker, now time.Time) RollingCounts {
+	m := cb.Metrics()
+	counts := RollingCounts{
+		Requests:                uint64(m.NumRequests().Sum(now)),
+		Errors:                  uint64(m.Errors().Sum(now)),
+		Successes:               uint64(m.Successes().Sum(now)),
+		Failures:                uint64(m.Failures().Sum(now)),
+		Rejects:                 uint64(m.Rejects().Sum(now)),
+		ShortCircuits:           uint64(m.ShortCircuits().Sum(now)),
+		Timeouts:                uint64(m.Timeouts().Sum(now)),
+		ContextCanceled:         uint64(m.ContextCanceled().Sum(now)),
+		ContextDeadlineExceeded: uint64(m.ContextDeadlineExceeded().Sum(now)),
+		FallbackSuccesses:       uint64(m.FallbackSuccesses().Sum(now)),
+		FallbackFailures:        uint64(m.FallbackFailures().Sum(now)),
+		FallbackRejections:      uint64(m.FallbackRejections().Sum(now)),
+		Hedges:                  uint64(m.Hedges().Sum(now)),
+	}
+	if counts.Requests > 0 {
+		counts.ErrorPercent = int(float64(counts.Errors)/float64(counts.Requests)*100 + 0.5)
+	}
+	return counts
+}
+
+// updateSettings applies the settings present in body to the current settings of the command,
+// leaving them untouched when the result is invalid.
+func (h *Handler) updateSettings(name string, body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("could not read settings: %v", err)
+	}
+
+	h.registry.UpdateSettings(name, func(s *hystrix.Settings) {
+		config := commandConfig(s)
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(&config); err != nil {
+			err = fmt.Errorf("invalid settings: %v", err)
+			return
+		}
+		if config.CommandGroup != s.CommandGroup {
+			err = fmt.Errorf("invalid settings: the command group cannot be changed")
+			return
+		}
+		if config.ThreadPoolKey != s.ThreadPoolKey {
+			err = fmt.Errorf("invalid settings: the thread pool key cannot be changed")
+			return
+		}
+		if err = validate(config); err != nil {
+			return
+		}
+		applyConfig(s, config)
+	})
+	return err
+}
+
+// validate checks the values of config, the rolling windows, half-open settings, fallback limit and key limits may be
+// zero for their defaults.
+func validate(config hystrix.CommandConfig) error {
+	switch {
+	case config.Timeout <= 0:
+		return fmt.Errorf("invalid settings: timeout must be positive")
+	case config.MaxConcurrentRequests <= 0:
+		return fmt.Errorf("invalid settings: max_concurrent_requests must be positive")
+	case config.QueueSizeRejectionThreshold < 0:
+		return fmt.Errorf("invalid settings: queue_size_rejection_threshold must not be negative")
+	case config.RequestVolumeThreshold < 0:
+		return fmt.Errorf("invalid settings: request_volume_threshold must not be negative")
+	case config.SleepWindow <= 0:
+		return fmt.Errorf("invalid settings: sleep_window must be positive")
+	case config.ErrorPercentThreshold <= 0 || config.ErrorPercentThreshold > 100:
+		return fmt.Errorf("invalid settings: error_percent_threshold must be between 1 and 100")
+	case !validWindow(config.MetricsRollingWindow, config.MetricsRollingBuckets):
+		return fmt.Errorf("invalid settings: metrics_rolling_window must be a positive multiple of metrics_rolling_buckets")
+	case !validWindow(config.MetricsRollingPercentileWindow, config.MetricsRollingPercentileBuckets):
+		return fmt.Errorf("invalid settings: metrics_rolling_percentile_window must be a positive multiple of metrics_rolling_percentile_buckets")
+	case config.HalfOpenMaxRequests < 0:
+		return fmt.Errorf("invalid settings: half_open_max_requests must not be negative")
+	case config.HalfOpenSuccessPercent < 0 || config.HalfOpenSuccessPercent > 100:
+		return fmt.Errorf("invalid settings: half_open_success_percent must be between 0 and 100")
+	case config.FallbackMaxConcurrentRequests < 0:
+		return fmt.Errorf("invalid settings: fallback_max_concurrent_requests must not be negative")
+	case config.MaxKeys < 0:
+		return fmt.Errorf("invalid settings: max_keys must not be negative")
+	case config.KeyIdleTimeout < 0:
+		return fmt.Errorf("invalid settings: key_idle_timeout must not be negative")
+	}
+	if _, ok := hystrix.ParseIsolationStrategy(config.IsolationStrategy); !ok {
+		return fmt.Errorf("invalid settings: isolation_strategy must be thread, semaphore or worker-pool")
+	}
+	return nil
+}
+
+func validWindow(window int, buckets int) bool {
+	if window == 0 && buckets == 0 {
+		return true
+	}
+	return window > 0 && buckets > 0 && window%buckets == 0
+}
+
+// commandConfig returns the settings in the format of a CommandConfig, with durations in milliseconds.
+func commandConfig(s *hystrix.Settings) hystrix.CommandConfig {
+	return hystrix.CommandConfig{
+		Timeout:                         int(s.Timeout / time.Millisecond),
+		CommandGroup:                    s.CommandGroup,
+		MaxConcurrentRequests:           s.MaxConcurrentRequests,
+		RequestVolumeThreshold:          int(s.RequestVolumeThreshold),
+		SleepWindow:                     int(s.SleepWindow / time.Millisecond),
+		ErrorPercentThreshold:           s.ErrorPercentThreshold,
+		QueueSizeRejectionThreshold:     s.QueueSizeRejectionThreshold,
+		MetricsRollingWindow:            int(s.MetricsRollingWindow / time.Millisecond),
+		MetricsRollingBuckets:           s.MetricsRollingBuckets,
+		MetricsRollingPercentileWindow:  int(s.MetricsRollingPercentileWindow / time.Millisecond),
+		MetricsRollingPercentileBuckets: s.MetricsRollingPercentileBuckets,
+		HalfOpenMaxRequests:             s.HalfOpenMaxRequests,
+		HalfOpenSuccessPercent:          s.HalfOpenSuccessPercent,
+		ForceOpen:                       s.ForceOpen,
+		ForceClosed:                     s.ForceClosed,
+		IsolationStrategy:               s.IsolationStrategy.String(),
+		ThreadPoolKey:                   s.ThreadPoolKey,
+		FallbackMaxConcurrentRequests:   s.FallbackMaxConcurrentRequests,
+		MaxKeys:                         s.MaxKeys,
+		KeyIdleTimeout:                  int(s.KeyIdleTimeout / time.Millisecond),
+	}
+}
+
+// applyConfig copies every value of config but the command group and thread pool key to s.
+func applyConfig(s *hystrix.Settings, config hystrix.CommandConfig) {
+	s.Timeout = time.Duration(config.Timeout) * time.Millisecond
+	s.MaxConcurrentRequests = config.MaxConcurrentRequests
+	s.RequestVolumeThreshold = uint64(config.RequestVolumeThreshold)
+	s.SleepWindow = time.Duration(config.SleepWindow) * time.Millisecond
+	s.ErrorPercentThreshold = config.ErrorPercentThreshold
+	s.QueueSizeRejectionThreshold = config.QueueSizeRejectionThreshold
+	s.MetricsRollingWindow = time.Duration(config.MetricsRollingWindow) * time.Millisecond
+	s.MetricsRollingBuckets = config.MetricsRollingBuckets
+	s.MetricsRollingPercentileWindow = time.Duration(config.MetricsRollingPercentileWindow) * time.Millisecond
+	s.MetricsRollingPercentileBuckets = config.MetricsRollingPercentileBuckets
+	s.HalfOpenMaxRequests = config.HalfOpenMaxRequests
+	s.HalfOpenSuccessPercent = config.HalfOpenSuccessPercent
+	s.ForceOpen = config.ForceOpen
+	s.ForceClosed = config.ForceClosed
+	s.IsolationStrategy, _ = hystrix.ParseIsolationStrategy(config.IsolationStrategy)
+	s.FallbackMaxConcurrentRequests = config.FallbackMaxConcurrentRequests
+	s.MaxKeys = config.MaxKeys
+	s.KeyIdleTimeout = time.Duration(config.KeyIdleTimeout) * time.Millisecond
+}
+
+// allowMethod answers requests of other methods with an error and returns whether the request may proceed.
+func allowMethod(rw http.ResponseWriter, req *http.Request, method string) bool {
+	if req.Method == method {
+		return true
+	}
+	rw.Header().Set("Allow", method)
+	writeError(rw, http.StatusMethodNotAllowed, fmt.Sprintf("method %v not allowed", req.Method))
+	return false
+}
+
+func writeError(rw http.ResponseWriter, status int, message string) {
+	writeJSON(rw, status, map[string]string{"error": message})
+}
+
+func writeJSON(rw http.ResponseWriter, status int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_ = json.NewEncoder(rw).Encode(v)
+}
@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// serve sends a request to handler and decodes the JSON response into v.
+func serve(handler http.Handler, method string, path string, body string, v interface{}) int {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	handler.ServeHTTP(rec, req)
+	if v != nil {
+		_ = json.Unmarshal(rec.Body.Bytes(), v)
+	}
+	return rec.Code
+}
+
+func TestHandler(t *testing.T) {
+	Convey("given a registry with two circuits", t, func() {
+		registry := hystrix.NewRegistry()
+		registry.ConfigureCommand("payments/charge", hystrix.CommandConfig{Timeout: 500, CommandGroup: "payments"})
+		So(registry.Do("payments/charge", func() error { return nil }, nil), ShouldBeNil)
+		So(registry.Do("search", func() error { return errors.New("search failed") }, nil), ShouldNotBeNil)
+		handler := NewRegistryHandler(registry)
+
+		cb, _, _ := registry.GetCircuit("search")
+		for cb.Metrics().NumRequests().Sum(time.Now()) < 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		Convey("GET /circuits lists them by name", func() {
+			var circuits []Circuit
+			So(serve(handler, http.MethodGet, "/circuits", "", &circuits), ShouldEqual, http.StatusOK)
+			So(len(circuits), ShouldEqual, 2)
+
+			So(circuits[0].Name, ShouldEqual, "payments/charge")
+			So(circuits[0].CommandGroup, ShouldEqual, "payments")
+			So(circuits[0].State, ShouldEqual, "closed")
+			So(circuits[0].Settings.Timeout, ShouldEqual, 500)
+
+			So(circuits[1].Name, ShouldEqual, "search")
+			So(circuits[1].Counts.Requests, ShouldEqual, 1)
+			So(circuits[1].Counts.Failures, ShouldEqual, 1)
+			So(circuits[1].Counts.ErrorPercent, ShouldEqual, 100)
+			So(circuits[1].Settings.MaxConcurrentRequests, ShouldEqual, hystrix.DefaultMaxConcurrent)
+			So(circuits[1].Pool.Limit, ShouldEqual, hystrix.DefaultMaxConcurrent)
+		})
+
+		Convey("GET /circuits/{name} returns an escaped circuit", func() {
+			var circuit Circuit
+			So(serve(handler, http.MethodGet, "/circuits/payments%2Fcharge", "", &circuit), ShouldEqual, http.StatusOK)
+			So(circuit.Name, ShouldEqual, "payments/charge")
+		})
+
+		Convey("unknown circuits are not found", func() {
+			var result map[string]string
+			So(serve(handler, http.MethodGet, "/circuits/unknown", "", &result), ShouldEqual, http.StatusNotFound)
+			So(result["error"], ShouldEqual, `unknown circuit "unknown"`)
+		})
+
+		Convey("POST /circuits/{name}/force-open forces the circuit open", func() {
+			var circuit Circuit
+			So(serve(handler, http.MethodPost, "/circuits/search/force-open", "", &circuit), ShouldEqual, http.StatusOK)
+			So(circuit.State, ShouldEqual, "open")
+			So(circuit.Settings.ForceOpen, ShouldBeTrue)
+			So(cb.IsOpen(), ShouldBeTrue)
+
+			Convey("and clear-force lets the health checks decide again", func() {
+				So(serve(handler, http.MethodPost, "/circuits/search/clear-force", "", &circuit), ShouldEqual, http.StatusOK)
+				So(circuit.Settings.ForceOpen, ShouldBeFalse)
+			})
+		})
+
+		Convey("POST /circuits/{name}/force-closed forces the circuit closed", func() {
+			var circuit Circuit
+			So(serve(handler, http.MethodPost, "/circuits/search/force-closed", "", &circuit), ShouldEqual, http.StatusOK)
+			So(circuit.Settings.ForceClosed, ShouldBeTrue)
+		})
+
+		Convey("POST /circuits/{name}/reset clears the rolling counts", func() {
+			var circuit Circuit
+			So(serve(handler, http.MethodPost, "/circuits/search/reset", "", &circuit), ShouldEqual, http.StatusOK)
+			So(circuit.Counts.Requests, ShouldEqual, 0)
+		})
+
+		Convey("POST /circuits/{name}/settings changes only the given settings", func() {
+			var circuit Circuit
+			So(serve(handler, http.MethodPost, "/circuits/payments%2Fcharge/settings", `{"max_concurrent_requests": 42}`, &circuit), ShouldEqual, http.StatusOK)
+			So(circuit.Settings.MaxConcurrentRequests, ShouldEqual, 42)
+			So(circuit.Settings.Timeout, ShouldEqual, 500)
+			So(registry.GetCircuitSettings()["payments/charge"].MaxConcurrentRequests, ShouldEqual, 42)
+
+			Convey("and rejects invalid settings", func() {
+				var result map[string]string
+				So(serve(handler, http.MethodPost, "/circuits/payments%2Fcharge/settings", `{"timeout": -1}`, &result), ShouldEqual, http.StatusBadRequest)
+				So(result["error"], ShouldContainSubstring, "timeout must be positive")
+				So(serve(handler, http.MethodPost, "/circuits/payments%2Fcharge/settings", `{"half_open_success_percent": 101}`, &result), ShouldEqual, http.StatusBadRequest)
+				So(result["error"], ShouldContainSubstring, "half_open_success_percent must be between 0 and 100")
+				So(serve(handler, http.MethodPost, "/circuits/payments%2Fcharge/settings", `{"unknown": 1}`, nil), ShouldEqual, http.StatusBadRequest)
+				So(registry.GetCircuitSettings()["payments/charge"].Timeout, ShouldEqual, 500*time.Millisecond)
+			})
+		})
+
+		Convey("actions have to be posted", func() {
+			So(serve(handler, http.MethodGet, "/circuits/search/force-open", "", nil), ShouldEqual, http.StatusMethodNotAllowed)
+			So(cb.IsOpen(), ShouldBeFalse)
+		})
+
+		Convey("actions have to be posted as JSON", func() {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/circuits/search/force-open", nil))
+			So(rec.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+
+			rec = httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/circuits/search/force-open", nil)
+			req.Header.Set("Content-Type", "text/plain")
+			handler.ServeHTTP(rec, req)
+			So(rec.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+			So(cb.IsOpen(), ShouldBeFalse)
+		})
+
+		Convey("settings larger than the body limit are rejected", func() {
+			body := `{"timeout": 100` + strings.Repeat(" ", maxBodySize) + `}`
+			So(serve(handler, http.MethodPost, "/circuits/search/settings", body, nil), ShouldEqual, http.StatusBadRequest)
+			So(registry.GetCircuitSettings()["search"].Timeout, ShouldNotEqual, 100*time.Millisecond)
+		})
+	})
+}
@@ -6,103 +6,278 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
 )
 
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets every command run and opens once recent executions are unhealthy.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen short-circuits every command until the sleep window has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of trial commands run to decide whether the circuit closes or opens again.
+	CircuitHalfOpen
+)
+
+var circuitStateNames = map[CircuitState]string{
+	CircuitClosed:   "closed",
+	CircuitOpen:     "open",
+	CircuitHalfOpen: "half-open",
+}
+
+// String returns the name of the state, e.g. "half-open".
+func (s CircuitState) String() string {
+	if name, ok := circuitStateNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
 // CircuitBreaker is created for each ExecutorPool to track whether requests
 // should be attempted, or rejected if the Health of the circuit is too low.
 type CircuitBreaker struct {
-	Name                   string
-	CommandGroup           string
-	open                   bool
+	Name         string
+	CommandGroup string
+	// Key is the key of a circuit created by DoKeyed, whose settings are those of its command, it is empty for others
+	Key string
+	// command is the name of the command whose settings apply, which differs from Name for keyed circuits
+	command                string
+	state                  CircuitState
 	forceOpen              bool
+	forceClosed            bool
 	mutex                  *sync.RWMutex
 	openedOrLastTestedTime int64
-
+	// outcome of the trial commands admitted since the circuit became half-open
+	trialsStarted  int
+	trialSuccesses int
+	trialFailures  int
+	// trialRound counts the times the circuit became half-open, so that trials of an earlier round are ignored,
+	// and publicTrials is the number of trials of the round admitted by AllowRequest whose result was not reported
+	trialRound   int
+	publicTrials int
+	// fallbacksRunning is the number of fallbacks currently running, updated atomically
+	fallbacksRunning int32
+	// stateChanges are reported to the listeners in the order they happened
+	stateChanges stateChangeQueue
+
+	registry *Registry
+	// threadPool may be shared with other circuits, executorPool is its executor pool
+	threadPool   *threadPool
 	executorPool *bufferedExecutorPool
 	metrics      *metricExchange
 }
 
-var (
-	circuitBreakersMutex *sync.RWMutex
-	circuitBreakers      map[string]*CircuitBreaker
-)
-
-func init() {
-	circuitBreakersMutex = &sync.RWMutex{}
-	circuitBreakers = make(map[string]*CircuitBreaker)
+// GetCircuit returns the circuit for the given command and whether this call created it.
+func GetCircuit(name string) (*CircuitBreaker, bool, error) {
+	return defaultRegistry.GetCircuit(name)
 }
 
 // GetCircuit returns the circuit for the given command and whether this call created it.
-func GetCircuit(name string) (*CircuitBreaker, bool, error) {
-	circuitBreakersMutex.RLock()
-	_, ok := circuitBreakers[name]
+func (r *Registry) GetCircuit(name string) (*CircuitBreaker, bool, error) {
+	r.circuitBreakersMutex.RLock()
+	_, ok := r.circuitBreakers[name]
 	if !ok {
-		circuitBreakersMutex.RUnlock()
-		circuitBreakersMutex.Lock()
-		defer circuitBreakersMutex.Unlock()
+		r.circuitBreakersMutex.RUnlock()
+		r.circuitBreakersMutex.Lock()
+		defer r.circuitBreakersMutex.Unlock()
 		// because we released the rlock before we obtained the exclusive lock,
 		// we need to double check that some other thread didn't beat us to
 		// creation.
-		if cb, present := circuitBreakers[name]; present {
+		if cb, present := r.circuitBreakers[name]; present {
 			return cb, false, nil
 		}
-		circuitBreakers[name] = newCircuitBreaker(name)
+		r.circuitBreakers[name] = newCircuitBreaker(r, name, "")
 	} else {
-		defer circuitBreakersMutex.RUnlock()
+		defer r.circuitBreakersMutex.RUnlock()
 	}
 
-	return circuitBreakers[name], !ok, nil
+	return r.circuitBreakers[name], !ok, nil
 }
 
 // Flush purges all circuit and metric information from memory.
 func Flush() {
-	circuitBreakersMutex.Lock()
-	defer circuitBreakersMutex.Unlock()
+	defaultRegistry.Flush()
+}
+
+// Flush purges all circuit and metric information of this registry from memory.
+func (r *Registry) Flush() {
+	r.circuitBreakersMutex.Lock()
+	defer r.circuitBreakersMutex.Unlock()
 
-	for name, cb := range circuitBreakers {
+	for name, cb := range r.circuitBreakers {
 		cb.metrics.Reset()
-		cb.executorPool.Metrics.Reset()
-		delete(circuitBreakers, name)
+		delete(r.circuitBreakers, name)
+	}
+	r.flushThreadPools()
+	r.flushKeyedCircuits()
+
+	r.retryBudgetsMutex.Lock()
+	r.retryBudgets = make(map[retryBudgetKey]*retryBudget)
+	r.retryBudgetsMutex.Unlock()
+}
+
+// Circuits returns the circuits created so far, keyed by command name.
+func Circuits() map[string]*CircuitBreaker {
+	return defaultRegistry.Circuits()
+}
+
+// Circuits returns the circuits of this registry created so far, keyed by command name.
+func (r *Registry) Circuits() map[string]*CircuitBreaker {
+	r.circuitBreakersMutex.RLock()
+	defer r.circuitBreakersMutex.RUnlock()
+
+	circuits := make(map[string]*CircuitBreaker, len(r.circuitBreakers))
+	for name, cb := range r.circuitBreakers {
+		circuits[name] = cb
 	}
+	return circuits
 }
 
-// newCircuitBreaker creates a CircuitBreaker with associated Health
-func newCircuitBreaker(name string) *CircuitBreaker {
+// newCircuitBreaker creates a CircuitBreaker with associated Health for the given command, or for one of its
+// keys with an executor pool of its own when key is not empty.
+func newCircuitBreaker(registry *Registry, command string, key string) *CircuitBreaker {
+	settings := registry.getSettings(command)
 	c := &CircuitBreaker{}
-	c.Name = name
-	commandGroup := getSettings(name).CommandGroup
-	c.CommandGroup = commandGroup
-	c.metrics = newMetricExchange(name, commandGroup)
-	c.executorPool = newBufferedExecutorPool(name)
+	c.Name = command
+	c.Key = key
+	c.command = command
+	c.registry = registry
+	c.CommandGroup = settings.CommandGroup
+	if key == "" {
+		c.threadPool = registry.getThreadPool(command)
+	} else {
+		c.Name = keyedCircuitName(command, key)
+		c.threadPool = newThreadPool(c.Name, settings)
+	}
+	c.metrics = newMetricExchange(registry, c.Name, settings)
+	c.executorPool = c.threadPool.executor
 	c.mutex = &sync.RWMutex{}
+	c.forceOpen = settings.ForceOpen
+	c.forceClosed = settings.ForceClosed
 
 	return c
 }
 
-// toggleForceOpen allows manually causing the fallback logic for all instances
-// of a given command.
-func (circuit *CircuitBreaker) toggleForceOpen(toggle bool) error {
-	circuit, _, err := GetCircuit(circuit.Name)
-	if err != nil {
-		return err
+// settings returns the current settings of the command of the circuit.
+func (circuit *CircuitBreaker) settings() *Settings {
+	return circuit.registry.getSettings(circuit.command)
+}
+
+// ForceOpen makes the circuit of the given command short-circuit every command until ClearForce is called.
+func ForceOpen(name string) {
+	defaultRegistry.ForceOpen(name)
+}
+
+// ForceOpen makes the circuit of the given command of this registry short-circuit every command
+// until ClearForce is called.
+func (r *Registry) ForceOpen(name string) {
+	r.UpdateSettings(name, func(s *Settings) {
+		s.ForceOpen = true
+		s.ForceClosed = false
+	})
+}
+
+// ForceClosed makes the circuit of the given command run every command, however unhealthy, until ClearForce is called.
+func ForceClosed(name string) {
+	defaultRegistry.ForceClosed(name)
+}
+
+// ForceClosed makes the circuit of the given command of this registry run every command, however unhealthy,
+// until ClearForce is called.
+func (r *Registry) ForceClosed(name string) {
+	r.UpdateSettings(name, func(s *Settings) {
+		s.ForceOpen = false
+		s.ForceClosed = true
+	})
+}
+
+// ClearForce lets the health of the circuit of the given command decide whether it is open again.
+func ClearForce(name string) {
+	defaultRegistry.ClearForce(name)
+}
+
+// ClearForce lets the health of the circuit of the given command of this registry decide whether it is open again.
+func (r *Registry) ClearForce(name string) {
+	r.UpdateSettings(name, func(s *Settings) {
+		s.ForceOpen = false
+		s.ForceClosed = false
+	})
+}
+
+// ForceOpen makes the circuit short-circuit every command until ClearForce is called.
+// This is stored in the settings of the circuit, see Registry.ForceOpen. Keyed circuits share the settings
+// of their command, so they are forced on their own until those settings change.
+func (circuit *CircuitBreaker) ForceOpen() {
+	if circuit.Key != "" {
+		circuit.setForce(true, false)
+		return
 	}
+	circuit.registry.ForceOpen(circuit.Name)
+}
 
-	circuit.forceOpen = toggle
-	return nil
+// ForceClosed makes the circuit run every command, however unhealthy, until ClearForce is called.
+// Metrics are still collected. This is stored in the settings of the circuit, see Registry.ForceClosed.
+func (circuit *CircuitBreaker) ForceClosed() {
+	if circuit.Key != "" {
+		circuit.setForce(false, true)
+		return
+	}
+	circuit.registry.ForceClosed(circuit.Name)
+}
+
+// ClearForce lets the health of the circuit decide whether it is open again.
+func (circuit *CircuitBreaker) ClearForce() {
+	if circuit.Key != "" {
+		circuit.setForce(false, false)
+		return
+	}
+	circuit.registry.ClearForce(circuit.Name)
+}
+
+// setForce applies the ForceOpen and ForceClosed settings to the circuit, ForceOpen wins when both are set.
+func (circuit *CircuitBreaker) setForce(open bool, closed bool) {
+	circuit.notifyStateChange(circuit.applyForce(open, closed))
+}
+
+// applyForce is like setForce, but returns the change for the listeners instead of notifying them.
+func (circuit *CircuitBreaker) applyForce(open bool, closed bool) *StateChange {
+	circuit.mutex.Lock()
+	defer circuit.mutex.Unlock()
+
+	from := circuit.effectiveState()
+	circuit.forceOpen = open
+	circuit.forceClosed = closed
+	return circuit.stateChange(from, true)
+}
+
+// forced returns whether the circuit is forced open or closed.
+func (circuit *CircuitBreaker) forced() (open bool, closed bool) {
+	circuit.mutex.RLock()
+	defer circuit.mutex.RUnlock()
+
+	return circuit.forceOpen, circuit.forceClosed
 }
 
 // IsOpen is called before any Command execution to check whether or
-// not it should be attempted. An "open" circuit means it is disabled.
+// not it should be attempted. An "open" circuit means it is disabled,
+// which includes a half-open circuit running its trial commands.
 func (circuit *CircuitBreaker) IsOpen() bool {
 	circuit.mutex.RLock()
-	o := circuit.forceOpen || circuit.open
+	o := circuit.effectiveState() != CircuitClosed
+	forceClosed := circuit.forceClosed && !circuit.forceOpen
 	circuit.mutex.RUnlock()
 
 	if o {
 		return true
 	}
+	if forceClosed {
+		return false
+	}
 
-	if uint64(circuit.metrics.Requests().Sum(time.Now())) < getSettings(circuit.Name).RequestVolumeThreshold {
+	if uint64(circuit.metrics.Requests().Sum(time.Now())) < circuit.settings().RequestVolumeThreshold {
 		return false
 	}
 
@@ -115,77 +290,305 @@ func (circuit *CircuitBreaker) IsOpen() bool {
 	return false
 }
 
+// State returns whether the circuit is closed, open or half-open.
+// A circuit which is forced open is open, one which is forced closed is closed.
+func (circuit *CircuitBreaker) State() CircuitState {
+	circuit.mutex.RLock()
+	defer circuit.mutex.RUnlock()
+
+	return circuit.effectiveState()
+}
+
+// effectiveState is the state commands observe, the caller must hold the mutex.
+func (circuit *CircuitBreaker) effectiveState() CircuitState {
+	if circuit.forceOpen {
+		return CircuitOpen
+	}
+	if circuit.forceClosed {
+		return CircuitClosed
+	}
+	return circuit.state
+}
+
 // AllowRequest is checked before a command executes, ensuring that circuit state and metric health allow it.
 // When the circuit is open, this call will occasionally return true to measure whether the external service
 // has recovered.
+// A request allowed this way while the circuit is half-open is one of its trials, whose outcome is the next
+// result passed to ReportEvent or ReportResult.
 func (circuit *CircuitBreaker) AllowRequest() bool {
-	return !circuit.IsOpen() || circuit.allowSingleTest()
+	allowed, round := circuit.allowRequest()
+	if round != 0 {
+		circuit.mutex.Lock()
+		if round == circuit.trialRound {
+			circuit.publicTrials++
+		}
+		circuit.mutex.Unlock()
+	}
+	return allowed
 }
 
-func (circuit *CircuitBreaker) allowSingleTest() bool {
-	circuit.mutex.RLock()
-	defer circuit.mutex.RUnlock()
+// allowRequest is AllowRequest which also returns the round of the half-open circuit the command is a trial of,
+// zero when it is not a trial. The outcome of trials has to be passed to reportTrial.
+func (circuit *CircuitBreaker) allowRequest() (allowed bool, trialRound int) {
+	if !circuit.IsOpen() {
+		return true, 0
+	}
+	return circuit.allowTrial()
+}
+
+// acquireFallback takes one of the FallbackMaxConcurrentRequests fallback slots of the circuit, and returns
+// false when they are all taken. Taken slots must be given back with releaseFallback.
+func (circuit *CircuitBreaker) acquireFallback() bool {
+	max := int32(circuit.settings().fallbackMaxConcurrentRequests())
+	if atomic.AddInt32(&circuit.fallbacksRunning, 1) > max {
+		atomic.AddInt32(&circuit.fallbacksRunning, -1)
+		return false
+	}
+	return true
+}
+
+func (circuit *CircuitBreaker) releaseFallback() {
+	atomic.AddInt32(&circuit.fallbacksRunning, -1)
+}
+
+// ActiveCount returns the number of commands currently executing on the thread pool of the circuit,
+// including those of the circuits sharing it.
+func (circuit *CircuitBreaker) ActiveCount() int {
+	active := circuit.executorPool.ActiveCount()
+	if workers := circuit.startedWorkerPool(); workers != nil {
+		active += workers.ActiveCount()
+	}
+	return active
+}
+
+// WaitingCount returns the number of commands queued for an execution slot of the thread pool of the circuit,
+// including those of the circuits sharing it.
+func (circuit *CircuitBreaker) WaitingCount() int {
+	waiting := circuit.executorPool.WaitingCount()
+	if workers := circuit.startedWorkerPool(); workers != nil {
+		waiting += workers.WaitingCount()
+	}
+	return waiting
+}
+
+// ConcurrencyLimit returns how many commands the thread pool of the circuit runs at the same time, which is
+// MaxConcurrentRequests unless the pool follows an AdaptiveLimit.
+func (circuit *CircuitBreaker) ConcurrencyLimit() int {
+	max, _ := circuit.executorPool.Size()
+	return max
+}
+
+// workerPool returns the worker pool of the thread pool of the circuit, starting it on first use.
+func (circuit *CircuitBreaker) workerPool() *workerPool {
+	return circuit.threadPool.workerPool(circuit.settings())
+}
 
+// startedWorkerPool returns the worker pool of the thread pool of the circuit, or nil when no command has used it yet.
+func (circuit *CircuitBreaker) startedWorkerPool() *workerPool {
+	return circuit.threadPool.startedWorkerPool()
+}
+
+// Metrics returns the collector of the circuit which its health checks are based on, e.g. to read its rolling counts.
+// Its rolling numbers must only be read.
+func (circuit *CircuitBreaker) Metrics() *metricCollector.DefaultMetricCollector {
+	return circuit.metrics.DefaultCollector()
+}
+
+// ResetMetrics clears the rolling metrics of the circuit and of its executor pool, and resets its metric collectors.
+// The executor pool metrics are those of every circuit sharing its thread pool.
+func (circuit *CircuitBreaker) ResetMetrics() {
+	circuit.metrics.Reset()
+	circuit.executorPool.Metrics.Reset()
+}
+
+// allowTrial moves an open circuit to half-open once the sleep window has passed, and admits trial commands
+// until HalfOpenMaxRequests of them are running or done. It returns whether the command was admitted and the
+// round of the half-open circuit it is a trial of. A half-open circuit whose trials did not all report within
+// the half-open timeout opens again.
+func (circuit *CircuitBreaker) allowTrial() (bool, int) {
+	settings := circuit.settings()
 	now := time.Now().UnixNano()
-	openedOrLastTestedTime := atomic.LoadInt64(&circuit.openedOrLastTestedTime)
-	if circuit.open && now > openedOrLastTestedTime+getSettings(circuit.Name).SleepWindow.Nanoseconds() {
-		swapped := atomic.CompareAndSwapInt64(&circuit.openedOrLastTestedTime, openedOrLastTestedTime, now)
-		if swapped {
-			log.Printf("hystrix-go: allowing single test to possibly close circuit %v", circuit.Name)
+
+	circuit.mutex.RLock()
+	waiting := circuit.state == CircuitOpen && now <= circuit.openedOrLastTestedTime+settings.SleepWindow.Nanoseconds()
+	circuit.mutex.RUnlock()
+	// most commands of an open circuit are rejected here without taking the write lock
+	if waiting {
+		return false, 0
+	}
+
+	var change *StateChange
+	// listeners are notified once the mutex is released
+	defer func() { circuit.notifyStateChange(change) }()
+
+	circuit.mutex.Lock()
+	defer circuit.mutex.Unlock()
+
+	if circuit.forceOpen {
+		return false, 0
+	}
+
+	switch circuit.state {
+	case CircuitOpen:
+		if now <= circuit.openedOrLastTestedTime+settings.SleepWindow.Nanoseconds() {
+			return false, 0
 		}
-		return swapped
+		log.Printf("hystrix-go: allowing %d trial requests to possibly close circuit %v", settings.halfOpenMaxRequests(), circuit.Name)
+
+		circuit.state = CircuitHalfOpen
+		circuit.openedOrLastTestedTime = now
+		circuit.trialRound++
+		circuit.trialsStarted = 1
+		circuit.trialSuccesses = 0
+		circuit.trialFailures = 0
+		circuit.publicTrials = 0
+		change = circuit.stateChange(CircuitOpen, false)
+		return true, circuit.trialRound
+	case CircuitHalfOpen:
+		if circuit.trialsStarted >= settings.halfOpenMaxRequests() {
+			// a trial which never reports, e.g. one allowed by AllowRequest whose result is dropped,
+			// must not keep the circuit half-open forever
+			if now > circuit.openedOrLastTestedTime+settings.halfOpenTimeout().Nanoseconds() {
+				change = circuit.open()
+			}
+			return false, 0
+		}
+		circuit.trialsStarted++
+		return true, circuit.trialRound
 	}
 
-	return false
+	return false, 0
 }
 
-func (circuit *CircuitBreaker) setOpen() {
+// reportTrial records the outcome of a trial command of the given round. The circuit closes once enough trials
+// succeeded to reach HalfOpenSuccessPercent of HalfOpenMaxRequests and opens again as soon as too many failed to reach it.
+func (circuit *CircuitBreaker) reportTrial(result *ExecutionResult, round int) {
+	settings := circuit.settings()
+	maxRequests := settings.halfOpenMaxRequests()
+	// the number of successes needed, rounded up
+	required := (maxRequests*settings.halfOpenSuccessPercent() + 99) / 100
+
+	var change *StateChange
+	// listeners are notified once the mutex is released
+	defer func() { circuit.notifyStateChange(change) }()
+
 	circuit.mutex.Lock()
 	defer circuit.mutex.Unlock()
 
-	if circuit.open {
+	if circuit.state != CircuitHalfOpen || round != circuit.trialRound {
 		return
 	}
 
-	log.Printf("hystrix-go: opening circuit %v", circuit.Name)
+	switch {
+	case result.HasEvent(EventSuccess):
+		circuit.trialSuccesses++
+	case result.HasEvent(EventContextCanceled) || result.HasEvent(EventContextDeadlineExceeded) || result.HasEvent(EventRejected):
+		// the caller gave up or the executor pool had no room for the trial, which says nothing
+		// about the health of the circuit, so let another command take the trial
+		circuit.trialsStarted--
+		return
+	default:
+		circuit.trialFailures++
+	}
 
-	circuit.openedOrLastTestedTime = time.Now().UnixNano()
-	circuit.open = true
+	if circuit.trialSuccesses >= required {
+		change = circuit.close()
+	} else if circuit.trialFailures > maxRequests-required {
+		change = circuit.open()
+	}
 }
 
-func (circuit *CircuitBreaker) setClose() {
+func (circuit *CircuitBreaker) setOpen() {
+	var change *StateChange
+	// listeners are notified once the mutex is released
+	defer func() { circuit.notifyStateChange(change) }()
+
 	circuit.mutex.Lock()
 	defer circuit.mutex.Unlock()
 
-	if !circuit.open {
+	if circuit.state != CircuitClosed {
 		return
 	}
 
+	change = circuit.open()
+}
+
+// open moves the circuit to CircuitOpen and returns the change for the listeners, the caller must hold the mutex.
+func (circuit *CircuitBreaker) open() *StateChange {
+	log.Printf("hystrix-go: opening circuit %v", circuit.Name)
+
+	from := circuit.effectiveState()
+	circuit.openedOrLastTestedTime = time.Now().UnixNano()
+	circuit.state = CircuitOpen
+	return circuit.stateChange(from, false)
+}
+
+// close moves the circuit to CircuitClosed with fresh metrics and returns the change for the listeners,
+// the caller must hold the mutex.
+func (circuit *CircuitBreaker) close() *StateChange {
 	log.Printf("hystrix-go: closing circuit %v", circuit.Name)
 
-	circuit.open = false
+	from := circuit.effectiveState()
+	circuit.state = CircuitClosed
+	// the change reports the metrics which closed the circuit
+	change := circuit.stateChange(from, false)
 	circuit.metrics.Reset()
+	return change
 }
 
 // ReportEvent records command metrics for tracking recent error rates and exposing data to the dashboard.
+// The event types are the names of EventType values, e.g. "success" or "short-circuit".
 func (circuit *CircuitBreaker) ReportEvent(eventTypes []string, start time.Time, runDuration time.Duration) error {
 	if len(eventTypes) == 0 {
 		return fmt.Errorf("no event types sent for metrics")
 	}
 
-	circuit.mutex.RLock()
-	o := circuit.open
-	circuit.mutex.RUnlock()
-	if eventTypes[0] == "success" && o {
-		circuit.setClose()
+	events := make([]EventType, 0, len(eventTypes))
+	for _, name := range eventTypes {
+		event, ok := metricCollector.ParseEventType(name)
+		if !ok {
+			return fmt.Errorf("unknown event type %q", name)
+		}
+		events = append(events, event)
+	}
+
+	return circuit.ReportResult(&ExecutionResult{
+		Events:        events,
+		Start:         start,
+		RunDuration:   runDuration,
+		TotalDuration: time.Since(start),
+	})
+}
+
+// ReportResult records the result of an execution for tracking recent error rates and exposing data to the dashboard.
+// While trials allowed by AllowRequest are outstanding, the result is also the outcome of one of them.
+func (circuit *CircuitBreaker) ReportResult(result *ExecutionResult) error {
+	if len(result.Events) == 0 {
+		return fmt.Errorf("no event types sent for metrics")
+	}
+
+	circuit.mutex.Lock()
+	round := 0
+	if circuit.publicTrials > 0 {
+		circuit.publicTrials--
+		round = circuit.trialRound
+	}
+	circuit.mutex.Unlock()
+	if round != 0 {
+		circuit.reportTrial(result, round)
+	}
+
+	return circuit.reportResult(result)
+}
+
+// reportResult is ReportResult for commands run by the registry, which report their trials themselves.
+func (circuit *CircuitBreaker) reportResult(result *ExecutionResult) error {
+	if len(result.Events) == 0 {
+		return fmt.Errorf("no event types sent for metrics")
 	}
 
 	select {
-	case circuit.metrics.Updates <- &commandExecution{
-		Types:       eventTypes,
-		Start:       start,
-		RunDuration: runDuration,
-	}:
+	case circuit.metrics.Updates <- result:
 	default:
 		return CircuitError{Message: fmt.Sprintf("metrics channel (%v) is at capacity", circuit.Name)}
 	}
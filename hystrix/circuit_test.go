@@ -148,3 +148,183 @@ func TestReportEventMultiThreaded(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestHalfOpen(t *testing.T) {
+	Convey("given an open circuit letting 3 trial requests through, 2 of which must succeed", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("trials", CommandConfig{
+			SleepWindow:            10,
+			HalfOpenMaxRequests:    3,
+			HalfOpenSuccessPercent: 60,
+		})
+		cb, _, _ := registry.GetCircuit("trials")
+		cb.setOpen()
+		So(cb.State(), ShouldEqual, CircuitOpen)
+
+		Convey("commands short-circuit during the sleep window", func() {
+			So(cb.AllowRequest(), ShouldBeFalse)
+			So(cb.State(), ShouldEqual, CircuitOpen)
+		})
+
+		Convey("after the sleep window", func() {
+			time.Sleep(20 * time.Millisecond)
+
+			Convey("3 concurrent trials run and further commands short-circuit", func() {
+				block := make(chan struct{})
+				var started sync.WaitGroup
+				started.Add(3)
+				errChans := make([]chan error, 0, 3)
+				for i := 0; i < 3; i++ {
+					errChans = append(errChans, registry.Go("trials", func() error {
+						started.Done()
+						<-block
+						return nil
+					}, nil))
+				}
+				started.Wait()
+				So(cb.State(), ShouldEqual, CircuitHalfOpen)
+				So(registry.Do("trials", func() error { return nil }, nil), ShouldResemble, ErrCircuitOpen)
+
+				Convey("and the circuit closes once they succeed", func() {
+					close(block)
+					for cb.State() != CircuitClosed {
+						time.Sleep(time.Millisecond)
+					}
+					So(cb.IsOpen(), ShouldBeFalse)
+					So(registry.Do("trials", func() error { return nil }, nil), ShouldBeNil)
+				})
+			})
+
+			Convey("the circuit stays half-open while the trials may still succeed", func() {
+				_, round := cb.allowRequest()
+				So(round, ShouldBeGreaterThan, 0)
+				cb.reportTrial(&ExecutionResult{Events: []EventType{EventFailure}}, round)
+				So(cb.State(), ShouldEqual, CircuitHalfOpen)
+
+				Convey("and opens again once they cannot", func() {
+					_, round = cb.allowRequest()
+					So(round, ShouldBeGreaterThan, 0)
+					cb.reportTrial(&ExecutionResult{Events: []EventType{EventTimeout}}, round)
+					So(cb.State(), ShouldEqual, CircuitOpen)
+					So(cb.AllowRequest(), ShouldBeFalse)
+				})
+			})
+
+			Convey("a trial abandoned by its caller is given to another command", func() {
+				round := 0
+				for i := 0; i < 3; i++ {
+					var allowed bool
+					allowed, round = cb.allowRequest()
+					So(allowed, ShouldBeTrue)
+				}
+				So(cb.AllowRequest(), ShouldBeFalse)
+
+				cb.reportTrial(&ExecutionResult{Events: []EventType{EventContextCanceled}}, round)
+				So(cb.State(), ShouldEqual, CircuitHalfOpen)
+				So(cb.AllowRequest(), ShouldBeTrue)
+			})
+
+			Convey("a trial rejected by the executor pool is given to another command", func() {
+				round := 0
+				for i := 0; i < 3; i++ {
+					_, round = cb.allowRequest()
+				}
+				So(cb.AllowRequest(), ShouldBeFalse)
+
+				for i := 0; i < 3; i++ {
+					cb.reportTrial(&ExecutionResult{Events: []EventType{EventRejected}}, round)
+				}
+				So(cb.State(), ShouldEqual, CircuitHalfOpen)
+				So(cb.AllowRequest(), ShouldBeTrue)
+			})
+
+			Convey("trials which never report open the circuit again after the timeout", func() {
+				registry.UpdateSettings("trials", func(s *Settings) { s.Timeout = 10 * time.Millisecond })
+				for i := 0; i < 3; i++ {
+					So(cb.AllowRequest(), ShouldBeTrue)
+				}
+				So(cb.State(), ShouldEqual, CircuitHalfOpen)
+				time.Sleep(20 * time.Millisecond)
+
+				So(cb.AllowRequest(), ShouldBeFalse)
+				So(cb.State(), ShouldEqual, CircuitOpen)
+
+				Convey("and their late outcome does not count for the next trials", func() {
+					time.Sleep(20 * time.Millisecond)
+					_, round := cb.allowRequest()
+					So(cb.State(), ShouldEqual, CircuitHalfOpen)
+					for i := 0; i < 3; i++ {
+						cb.reportTrial(&ExecutionResult{Events: []EventType{EventFailure}}, round-1)
+					}
+					So(cb.State(), ShouldEqual, CircuitHalfOpen)
+				})
+			})
+		})
+	})
+
+	Convey("given an open circuit driven through AllowRequest and ReportEvent", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("public trials", CommandConfig{SleepWindow: 10, Timeout: 10})
+		cb, _, _ := registry.GetCircuit("public trials")
+		cb.setOpen()
+		time.Sleep(20 * time.Millisecond)
+
+		So(cb.AllowRequest(), ShouldBeTrue)
+		So(cb.State(), ShouldEqual, CircuitHalfOpen)
+		So(cb.AllowRequest(), ShouldBeFalse)
+
+		Convey("a successful trial closes it", func() {
+			So(cb.ReportEvent([]string{"success"}, time.Now(), time.Millisecond), ShouldBeNil)
+			So(cb.State(), ShouldEqual, CircuitClosed)
+			So(cb.AllowRequest(), ShouldBeTrue)
+		})
+
+		Convey("a failed trial opens it again", func() {
+			So(cb.ReportEvent([]string{"failure"}, time.Now(), time.Millisecond), ShouldBeNil)
+			So(cb.State(), ShouldEqual, CircuitOpen)
+		})
+	})
+}
+
+func TestForce(t *testing.T) {
+	Convey("given an open circuit", t, func() {
+		registry := NewRegistry()
+		cb, _, _ := registry.GetCircuit("forced")
+		cb.setOpen()
+
+		Convey("forcing it closed runs every command and keeps collecting metrics", func() {
+			registry.ForceClosed("forced")
+			So(registry.GetCircuitSettings()["forced"].ForceClosed, ShouldBeTrue)
+			So(cb.State(), ShouldEqual, CircuitClosed)
+			So(cb.IsOpen(), ShouldBeFalse)
+
+			So(registry.Do("forced", func() error { return nil }, nil), ShouldBeNil)
+			for cb.metrics.DefaultCollector().Successes().Sum(time.Now()) < 1 {
+				time.Sleep(time.Millisecond)
+			}
+
+			Convey("and clearing it lets the circuit short-circuit again", func() {
+				cb.ClearForce()
+				So(cb.State(), ShouldEqual, CircuitOpen)
+				So(registry.Do("forced", func() error { return nil }, nil), ShouldResemble, ErrCircuitOpen)
+			})
+		})
+
+		Convey("forcing it open and closed at once keeps it open", func() {
+			registry.UpdateSettings("forced", func(s *Settings) {
+				s.ForceOpen = true
+				s.ForceClosed = true
+			})
+			So(cb.IsOpen(), ShouldBeTrue)
+			So(cb.AllowRequest(), ShouldBeFalse)
+		})
+	})
+
+	Convey("a circuit created with ForceOpen set is forced open", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("forced", CommandConfig{ForceOpen: true})
+		cb, _, _ := registry.GetCircuit("forced")
+		So(cb.State(), ShouldEqual, CircuitOpen)
+		So(registry.Do("forced", func() error { return nil }, nil), ShouldResemble, ErrCircuitOpen)
+	})
+}
@@ -20,6 +20,33 @@ type CommandBuilder struct {
 	// group a number of command (circuit name) together, useful for defining ownership/alerts/monitoring
 	// ref: https://github.com/Netflix/Hystrix/wiki/How-To-Use#command-group
 	commandGroup string
+	// length of the rolling windows in milliseconds and the number of buckets they are divided into
+	// ref: https://github.com/Netflix/Hystrix/wiki/Configuration#metrics
+	metricsRollingWindow            int
+	metricsRollingBuckets           int
+	metricsRollingPercentileWindow  int
+	metricsRollingPercentileBuckets int
+	// trial requests let through by a half-open circuit and the percent of them which must succeed to close it
+	halfOpenMaxRequests    int
+	halfOpenSuccessPercent int
+	// short-circuit every command, or run every command however unhealthy the circuit is
+	forceOpen   bool
+	forceClosed bool
+	// run commands on their own goroutine or on the caller's
+	isolationStrategy hystrix.IsolationStrategy
+	// share the executor pool with the other commands of the same key
+	threadPoolKey string
+	// how many fallbacks can run at the same time
+	fallbackMaxConcurrentRequests int
+	// retry failed commands within their execution
+	retryPolicy *hystrix.RetryPolicy
+	// start a second attempt of slow commands
+	hedgePolicy *hystrix.HedgePolicy
+	// how many keyed circuits are kept, and how long, in milliseconds, one is kept without any command
+	maxKeys        int
+	keyIdleTimeout int
+	// adjust the concurrency limit from the latency and failures of commands
+	adaptiveLimit *hystrix.AdaptiveLimit
 }
 
 // New Create new command
@@ -33,6 +60,19 @@ func New(commandName string) *CommandBuilder {
 		sleepWindow:                 hystrix.DefaultSleepWindow,
 		errorPercentThreshold:       hystrix.DefaultErrorPercentThreshold,
 		queueSizeRejectionThreshold: nil, // will init later on build
+
+		metricsRollingWindow:            hystrix.DefaultMetricsRollingWindow,
+		metricsRollingBuckets:           hystrix.DefaultMetricsRollingBuckets,
+		metricsRollingPercentileWindow:  hystrix.DefaultMetricsRollingPercentileWindow,
+		metricsRollingPercentileBuckets: hystrix.DefaultMetricsRollingPercentileBuckets,
+
+		halfOpenMaxRequests:    hystrix.DefaultHalfOpenMaxRequests,
+		halfOpenSuccessPercent: hystrix.DefaultHalfOpenSuccessPercent,
+
+		fallbackMaxConcurrentRequests: hystrix.DefaultFallbackMaxConcurrent,
+
+		maxKeys:        hystrix.DefaultMaxKeys,
+		keyIdleTimeout: hystrix.DefaultKeyIdleTimeout,
 	}
 }
 
@@ -96,6 +136,96 @@ func (cb *CommandBuilder) WithQueueSize(queueSize int) *CommandBuilder {
 	return cb
 }
 
+// WithMetricsRollingWindow modify the rolling window used for health checks and metrics
+// the window must be a multiple of the number of buckets
+func (cb *CommandBuilder) WithMetricsRollingWindow(windowInMs int, buckets int) *CommandBuilder {
+	if windowInMs > 0 && buckets > 0 {
+		cb.metricsRollingWindow = windowInMs
+		cb.metricsRollingBuckets = buckets
+	}
+	return cb
+}
+
+// WithMetricsRollingPercentileWindow modify the rolling window used for latency percentiles
+// the window must be a multiple of the number of buckets
+func (cb *CommandBuilder) WithMetricsRollingPercentileWindow(windowInMs int, buckets int) *CommandBuilder {
+	if windowInMs > 0 && buckets > 0 {
+		cb.metricsRollingPercentileWindow = windowInMs
+		cb.metricsRollingPercentileBuckets = buckets
+	}
+	return cb
+}
+
+// WithHalfOpenRequests modify the number of trial requests let through once the sleep window has passed
+// and the percent of them which must succeed to close the circuit
+func (cb *CommandBuilder) WithHalfOpenRequests(maxRequests int, successPercent int) *CommandBuilder {
+	if maxRequests > 0 && successPercent > 0 && successPercent <= 100 {
+		cb.halfOpenMaxRequests = maxRequests
+		cb.halfOpenSuccessPercent = successPercent
+	}
+	return cb
+}
+
+// WithForceOpen modify whether the circuit short-circuits every command
+func (cb *CommandBuilder) WithForceOpen(forceOpen bool) *CommandBuilder {
+	cb.forceOpen = forceOpen
+	return cb
+}
+
+// WithForceClosed modify whether the circuit runs every command however unhealthy it is
+func (cb *CommandBuilder) WithForceClosed(forceClosed bool) *CommandBuilder {
+	cb.forceClosed = forceClosed
+	return cb
+}
+
+// WithIsolationStrategy modify whether commands run on their own goroutine, on the caller's or on a worker pool
+func (cb *CommandBuilder) WithIsolationStrategy(strategy hystrix.IsolationStrategy) *CommandBuilder {
+	cb.isolationStrategy = strategy
+	return cb
+}
+
+// WithThreadPoolKey modify the executor pool of the command, commands with the same key share it
+func (cb *CommandBuilder) WithThreadPoolKey(threadPoolKey string) *CommandBuilder {
+	cb.threadPoolKey = threadPoolKey
+	return cb
+}
+
+// WithFallbackMaxConcurrentRequests modify how many fallbacks can run at the same time
+func (cb *CommandBuilder) WithFallbackMaxConcurrentRequests(fallbackMaxConcurrentRequests int) *CommandBuilder {
+	cb.fallbackMaxConcurrentRequests = fallbackMaxConcurrentRequests
+	return cb
+}
+
+// WithRetryPolicy modify how failed commands are retried
+func (cb *CommandBuilder) WithRetryPolicy(policy hystrix.RetryPolicy) *CommandBuilder {
+	cb.retryPolicy = &policy
+	return cb
+}
+
+// WithHedgePolicy modify when a second attempt of slow commands is started
+func (cb *CommandBuilder) WithHedgePolicy(policy hystrix.HedgePolicy) *CommandBuilder {
+	cb.hedgePolicy = &policy
+	return cb
+}
+
+// WithMaxKeys modify how many keyed circuits of the command are kept
+func (cb *CommandBuilder) WithMaxKeys(maxKeys int) *CommandBuilder {
+	cb.maxKeys = maxKeys
+	return cb
+}
+
+// WithKeyIdleTimeout modify how long a keyed circuit is kept without any command
+func (cb *CommandBuilder) WithKeyIdleTimeout(keyIdleTimeoutInMs int) *CommandBuilder {
+	cb.keyIdleTimeout = keyIdleTimeoutInMs
+	return cb
+}
+
+// WithAdaptiveLimit modify how the concurrency limit follows the latency and failures of commands, up to maxConcurrentRequests
+func (cb *CommandBuilder) WithAdaptiveLimit(limit hystrix.AdaptiveLimit) *CommandBuilder {
+	cb.adaptiveLimit = &limit
+	return cb
+}
+
 // Build the command setting, Use hystrix.Initialize for setup
 func (cb *CommandBuilder) Build() *hystrix.Settings {
 
@@ -114,5 +244,30 @@ func (cb *CommandBuilder) Build() *hystrix.Settings {
 		RequestVolumeThreshold:      uint64(cb.requestVolumeThreshold),
 		SleepWindow:                 time.Duration(cb.sleepWindow) * time.Millisecond,
 		QueueSizeRejectionThreshold: *cb.queueSizeRejectionThreshold,
+
+		MetricsRollingWindow:            time.Duration(cb.metricsRollingWindow) * time.Millisecond,
+		MetricsRollingBuckets:           cb.metricsRollingBuckets,
+		MetricsRollingPercentileWindow:  time.Duration(cb.metricsRollingPercentileWindow) * time.Millisecond,
+		MetricsRollingPercentileBuckets: cb.metricsRollingPercentileBuckets,
+
+		HalfOpenMaxRequests:    cb.halfOpenMaxRequests,
+		HalfOpenSuccessPercent: cb.halfOpenSuccessPercent,
+
+		ForceOpen:   cb.forceOpen,
+		ForceClosed: cb.forceClosed,
+
+		IsolationStrategy: cb.isolationStrategy,
+
+		ThreadPoolKey: cb.threadPoolKey,
+
+		FallbackMaxConcurrentRequests: cb.fallbackMaxConcurrentRequests,
+
+		RetryPolicy: cb.retryPolicy,
+		HedgePolicy: cb.hedgePolicy,
+
+		MaxKeys:        cb.maxKeys,
+		KeyIdleTimeout: time.Duration(cb.keyIdleTimeout) * time.Millisecond,
+
+		AdaptiveLimit: cb.adaptiveLimit,
 	}
 }
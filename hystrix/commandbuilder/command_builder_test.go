@@ -121,3 +121,116 @@ func TestOverflowWithoutQueue(t *testing.T) {
 
 	})
 }
+
+func TestCommandBuilderWithMetricsRollingWindow(t *testing.T) {
+	Convey("given a command configured with custom rolling windows", t, func() {
+		commandSetting := New("command6").WithMetricsRollingWindow(30000, 30).WithMetricsRollingPercentileWindow(120000, 12).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the windows should be the same", func() {
+			circuits := hystrix.GetCircuitSettings()
+			So(circuits["command6"].MetricsRollingWindow, ShouldEqual, 30*time.Second)
+			So(circuits["command6"].MetricsRollingBuckets, ShouldEqual, 30)
+			So(circuits["command6"].MetricsRollingPercentileWindow, ShouldEqual, 2*time.Minute)
+			So(circuits["command6"].MetricsRollingPercentileBuckets, ShouldEqual, 12)
+		})
+	})
+}
+
+func TestCommandBuilderWithHalfOpenRequests(t *testing.T) {
+	Convey("given a command configured with several half-open trial requests", t, func() {
+		commandSetting := New("command7").WithHalfOpenRequests(5, 80).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the trial requests should be the same", func() {
+			circuits := hystrix.GetCircuitSettings()
+			So(circuits["command7"].HalfOpenMaxRequests, ShouldEqual, 5)
+			So(circuits["command7"].HalfOpenSuccessPercent, ShouldEqual, 80)
+		})
+	})
+}
+
+func TestCommandBuilderWithForceOpen(t *testing.T) {
+	Convey("given a command configured to be forced open", t, func() {
+		commandSetting := New("command8").WithForceOpen(true).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("its circuit should be open", func() {
+			So(hystrix.GetCircuitSettings()["command8"].ForceOpen, ShouldBeTrue)
+			cb, _, _ := hystrix.GetCircuit("command8")
+			So(cb.IsOpen(), ShouldBeTrue)
+		})
+	})
+}
+
+func TestCommandBuilderWithIsolationStrategy(t *testing.T) {
+	Convey("given a command configured for semaphore isolation", t, func() {
+		commandSetting := New("command9").WithIsolationStrategy(hystrix.IsolationSemaphore).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the isolation strategy should be the same", func() {
+			So(hystrix.GetCircuitSettings()["command9"].IsolationStrategy, ShouldEqual, hystrix.IsolationSemaphore)
+		})
+	})
+}
+
+func TestCommandBuilderWithThreadPoolKey(t *testing.T) {
+	Convey("given a command configured with a thread pool key", t, func() {
+		commandSetting := New("command10").WithThreadPoolKey("shared").Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the thread pool key should be the same", func() {
+			So(hystrix.GetCircuitSettings()["command10"].ThreadPoolKey, ShouldEqual, "shared")
+		})
+	})
+}
+
+func TestCommandBuilderWithFallbackMaxConcurrentRequests(t *testing.T) {
+	Convey("given a command configured with a fallback limit", t, func() {
+		commandSetting := New("command11").WithFallbackMaxConcurrentRequests(3).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the fallback limit should be the same", func() {
+			So(hystrix.GetCircuitSettings()["command11"].FallbackMaxConcurrentRequests, ShouldEqual, 3)
+		})
+	})
+}
+
+func TestCommandBuilderWithRetryPolicy(t *testing.T) {
+	Convey("given a command configured with a retry policy", t, func() {
+		commandSetting := New("command12").WithRetryPolicy(hystrix.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the retry policy should be the same", func() {
+			policy := hystrix.GetCircuitSettings()["command12"].RetryPolicy
+			So(policy.MaxAttempts, ShouldEqual, 3)
+			So(policy.InitialBackoff, ShouldEqual, time.Millisecond)
+		})
+	})
+}
+
+func TestCommandBuilderWithKeyLimits(t *testing.T) {
+	Convey("given a command configured with key limits", t, func() {
+		commandSetting := New("command13").WithMaxKeys(50).WithKeyIdleTimeout(60000).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the key limits should be the same", func() {
+			settings := hystrix.GetCircuitSettings()["command13"]
+			So(settings.MaxKeys, ShouldEqual, 50)
+			So(settings.KeyIdleTimeout, ShouldEqual, time.Minute)
+		})
+	})
+}
+
+func TestCommandBuilderWithAdaptiveLimit(t *testing.T) {
+	Convey("given a command configured with an adaptive limit", t, func() {
+		commandSetting := New("command14").WithAdaptiveLimit(hystrix.AdaptiveLimit{Algorithm: hystrix.LimitVegas, MinLimit: 2}).Build()
+		hystrix.Initialize(commandSetting)
+
+		Convey("reading the adaptive limit should be the same", func() {
+			limit := hystrix.GetCircuitSettings()["command14"].AdaptiveLimit
+			So(limit.Algorithm, ShouldEqual, hystrix.LimitVegas)
+			So(limit.MinLimit, ShouldEqual, 2)
+		})
+	})
+}
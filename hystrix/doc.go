@@ -29,6 +29,9 @@ This triggers when your code returns an error, or whenever it is unable to compl
 		return nil
 	})
 
+At most FallbackMaxConcurrentRequests fallbacks of a command run at the same time, others are rejected
+with ErrFallbackRejected.
+
 Waiting for output
 
 Calling Go is like launching a goroutine, except you receive a channel of errors you can choose to monitor.
@@ -56,6 +59,26 @@ Since calling a command and immediately waiting for it to finish is a common pat
 		return nil
 	}, nil)
 
+Context-aware API
+
+GoC and DoC accept a context which is passed to your run function. The context is cancelled when the
+command times out, and cancelling it yourself abandons the command without counting it as a failure.
+
+	err := hystrix.DoC(ctx, "my_command", func(ctx context.Context) error {
+		// talk to other services, stopping once ctx is done
+		return nil
+	}, nil)
+
+Typed results
+
+Execute returns the value of your run function, or of your fallback, removing the need for output channels.
+ExecuteAsync does the same without blocking and returns a Future.
+
+	user, err := hystrix.Execute(ctx, "my_command", func(ctx context.Context) (*User, error) {
+		// talk to other services
+		return fetchUser(ctx)
+	}, nil)
+
 Configure settings
 
 During application boot, you can call ConfigureCommand to tweak the settings for each command.
@@ -68,6 +91,75 @@ During application boot, you can call ConfigureCommand to tweak the settings for
 
 You can also use Configure which accepts a map[string]CommandConfig.
 
+Settings of existing circuits can be changed at runtime with UpdateSettings, or by configuring the command again.
+
+	hystrix.UpdateSettings("my_command", func(s *hystrix.Settings) {
+		s.MaxConcurrentRequests = 200
+	})
+
+Recovering circuits
+
+An open circuit short-circuits every command for the sleep window, then becomes half-open and lets
+HalfOpenMaxRequests trial commands run. It closes once HalfOpenSuccessPercent of them succeeded,
+and opens again as soon as too many failed or when they did not all report within the longest
+of Timeout and SleepWindow. State returns which of these states a circuit is in,
+and listeners added with OnStateChange are called on every transition.
+ForceOpen and ForceClosed take the decision away from the health checks until ClearForce is called.
+
+	hystrix.ConfigureCommand("my_command", hystrix.CommandConfig{
+		HalfOpenMaxRequests:    10,
+		HalfOpenSuccessPercent: 80,
+	})
+
+Retries and hedging
+
+Settings.RetryPolicy retries the run function of failed commands within their execution and Timeout, with
+exponential backoff, as long as the circuit is closed. Every failed attempt counts towards its error percent.
+A RetryBudget caps the retries of a circuit, or of its command group, at a percent of its recent requests.
+
+Settings.HedgePolicy starts a second attempt of commands which are slower than a delay, or than a percentile of
+the recent run durations, on an execution slot of its own. The first attempt to return wins.
+
+Semaphore isolation
+
+Circuits with IsolationStrategy set to IsolationSemaphore run the commands of Do and DoC on the calling goroutine,
+which saves the cost of the goroutines for cheap calls. Timeouts are then only enforced through the context.
+Circuits with IsolationWorkerPool run their commands on a fixed number of long-lived goroutines, which take them
+in order from a bounded queue. Commands with the same ThreadPoolKey share their executor pool, and so their
+concurrency limit, while keeping separate circuits.
+Settings.AdaptiveLimit adjusts that limit from the latency and failures of the commands, up to
+MaxConcurrentRequests, with LimitAIMD, LimitVegas or LimitGradient.
+
+Keyed circuits
+
+DoKeyed runs a command on a circuit of its own for a key such as the host or tenant it calls, so that one bad
+host does not open the circuit of the others. Keyed circuits share the settings of their command but have their
+own state and executor pool. MaxKeys bounds how many are kept, and those unused for KeyIdleTimeout are evicted.
+
+	err := hystrix.DoKeyed("payments", host, func() error {
+		// talk to host
+		return nil
+	}, nil)
+
+Independent registries
+
+The package level functions share one set of circuits. Libraries and tests which need their own circuits,
+settings and metric collectors can create a Registry, which offers the same functions as methods.
+
+	registry := hystrix.NewRegistry()
+	registry.ConfigureCommand("my_command", hystrix.CommandConfig{Timeout: 500})
+	err := registry.Do("my_command", func() error {
+		// talk to other services
+		return nil
+	}, nil)
+
+Intercepting executions
+
+An Interceptor is called at each phase of every execution, to trace, log or audit commands. Interceptors are
+added for all commands or for a single one, and embed NopInterceptor to implement only the hooks they need.
+
+	hystrix.AddCommandInterceptor("my_command", auditor)
+
 Enable dashboard metrics
 
 In your main.go, register the event stream HTTP handler on a port and launch it in a goroutine.  Once you configure turbine for your Hystrix Dashboard https://github.com/Netflix/Hystrix/tree/master/hystrix-dashboard to start streaming events, your commands will automatically begin appearing.
@@ -0,0 +1,28 @@
+package hystrix
+
+import (
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// EventType identifies something which happened during the execution of a command.
+type EventType = metricCollector.EventType
+
+// ExecutionResult describes a finished execution of a command, it is passed to interceptors
+// and to metric collectors implementing metricCollector.ResultCollector.
+type ExecutionResult = metricCollector.ExecutionResult
+
+// The events reported by command executions.
+const (
+	EventSuccess                 = metricCollector.EventSuccess
+	EventFailure                 = metricCollector.EventFailure
+	EventTimeout                 = metricCollector.EventTimeout
+	EventShortCircuit            = metricCollector.EventShortCircuit
+	EventRejected                = metricCollector.EventRejected
+	EventQueued                  = metricCollector.EventQueued
+	EventContextCanceled         = metricCollector.EventContextCanceled
+	EventContextDeadlineExceeded = metricCollector.EventContextDeadlineExceeded
+	EventFallbackSuccess         = metricCollector.EventFallbackSuccess
+	EventFallbackFailure         = metricCollector.EventFallbackFailure
+	EventFallbackRejection       = metricCollector.EventFallbackRejection
+	EventHedged                  = metricCollector.EventHedged
+)
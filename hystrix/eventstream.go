@@ -14,28 +14,54 @@ const (
 	streamEventBufferSize = 10
 )
 
+// DefaultStreamInterval is how often a StreamHandler publishes metrics when no Interval is set.
+var DefaultStreamInterval = 1 * time.Second
+
 // NewStreamHandler returns a server capable of exposing dashboard metrics via HTTP.
 func NewStreamHandler() *StreamHandler {
-	return &StreamHandler{}
+	return defaultRegistry.NewStreamHandler()
+}
+
+// NewStreamHandler returns a server capable of exposing dashboard metrics of the circuits of this registry via HTTP.
+func (r *Registry) NewStreamHandler() *StreamHandler {
+	return &StreamHandler{registry: r}
 }
 
-// StreamHandler publishes metrics for each command and each pool once a second to all connected HTTP client.
+// StreamHandler publishes metrics for each command and each pool once every Interval to all connected HTTP client.
 type StreamHandler struct {
+	// Interval is the time between two publications of metrics, DefaultStreamInterval is used when it is zero.
+	// It must be set before calling Start.
+	Interval time.Duration
+
+	registry *Registry
 	requests map[*http.Request]chan []byte
 	mu       sync.RWMutex
 	done     chan struct{}
+	stopOnce sync.Once
+	stopped  sync.WaitGroup
 }
 
 // Start begins watching the in-memory circuit breakers for metrics
 func (sh *StreamHandler) Start() {
 	sh.requests = make(map[*http.Request]chan []byte)
 	sh.done = make(chan struct{})
-	//go sh.loop()
+
+	interval := sh.Interval
+	if interval <= 0 {
+		interval = DefaultStreamInterval
+	}
+
+	sh.stopped.Add(1)
+	go sh.loop(interval)
 }
 
-// Stop shuts down the metric collection routine
+// Stop shuts down the metric collection routine and disconnects all clients.
+// It waits for the collection routine to exit.
 func (sh *StreamHandler) Stop() {
-	close(sh.done)
+	sh.stopOnce.Do(func() {
+		close(sh.done)
+	})
+	sh.stopped.Wait()
 }
 
 var _ http.Handler = (*StreamHandler)(nil)
@@ -50,16 +76,17 @@ func (sh *StreamHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	events := sh.register(req)
 	defer sh.unregister(req)
 
-	notify := rw.(http.CloseNotifier).CloseNotify()
-
 	rw.Header().Add("Content-Type", "text/event-stream")
 	rw.Header().Set("Cache-Control", "no-cache")
 	rw.Header().Set("Connection", "keep-alive")
 	for {
 		select {
-		case <-notify:
+		case <-req.Context().Done():
 			// client is gone
 			return
+		case <-sh.done:
+			// the handler is stopped, disconnect the client
+			return
 		case event := <-events:
 			_, err := rw.Write(event)
 			if err != nil {
@@ -70,17 +97,26 @@ func (sh *StreamHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	}
 }
 
-func (sh *StreamHandler) loop() {
-	tick := time.Tick(1 * time.Second)
+func (sh *StreamHandler) loop(interval time.Duration) {
+	defer sh.stopped.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
 	for {
 		select {
-		case <-tick:
-			circuitBreakersMutex.RLock()
-			for _, cb := range circuitBreakers {
+		case <-ticker.C:
+			sh.registry.circuitBreakersMutex.RLock()
+			// thread pools shared by several circuits are published once
+			published := make(map[*threadPool]bool)
+			for _, cb := range sh.registry.circuitBreakers {
 				_ = sh.publishMetrics(cb)
-				_ = sh.publishThreadPools(cb.executorPool)
+				if !published[cb.threadPool] {
+					published[cb.threadPool] = true
+					_ = sh.publishThreadPools(cb)
+				}
 			}
-			circuitBreakersMutex.RUnlock()
+			sh.registry.circuitBreakersMutex.RUnlock()
 		case <-sh.done:
 			return
 		}
@@ -89,20 +125,37 @@ func (sh *StreamHandler) loop() {
 
 func (sh *StreamHandler) publishMetrics(cb *CircuitBreaker) error {
 	now := time.Now()
+	// circuits configured through the command builder may not have a group, the dashboard requires one
+	commandGroup := cb.CommandGroup
+	if commandGroup == "" {
+		commandGroup = cb.Name
+	}
+	rollingWindow, _ := sh.registry.getSettings(cb.Name).rollingWindow()
 	reqCount := cb.metrics.Requests().Sum(now)
 	errCount := cb.metrics.DefaultCollector().Errors().Sum(now)
 	errPct := cb.metrics.ErrorPercent(now)
+	forceOpen, forceClosed := cb.forced()
+	settings := sh.registry.getSettings(cb.Name)
+	// the dashboard counts rejections, and the limit of semaphore isolated circuits, separately
+	rejects := uint32(cb.metrics.DefaultCollector().Rejects().Sum(now))
+	var threadPoolRejects, semaphoreRejects, semaphoreMax uint32
+	if settings.IsolationStrategy == IsolationSemaphore {
+		semaphoreRejects = rejects
+		semaphoreMax = uint32(settings.MaxConcurrentRequests)
+	} else {
+		threadPoolRejects = rejects
+	}
 
 	eventBytes, err := json.Marshal(&streamCmdMetric{
 		Type:               "HystrixCommand",
 		Name:               cb.Name,
-		Group:              cb.Name,
+		Group:              commandGroup,
 		Time:               currentTime(),
 		ReportingHosts:     1,
-		LatencyTotal:       generateLatencyTimings(cb.metrics.DefaultCollector().TotalDuration()),
-		LatencyTotalMean:   cb.metrics.DefaultCollector().TotalDuration().Mean(),
-		LatencyExecute:     generateLatencyTimings(cb.metrics.DefaultCollector().RunDuration()),
-		LatencyExecuteMean: cb.metrics.DefaultCollector().RunDuration().Mean(),
+		LatencyTotal:       generateLatencyTimings(cb.metrics.DefaultCollector().TotalDurationDistribution()),
+		LatencyTotalMean:   cb.metrics.DefaultCollector().TotalDurationDistribution().Mean(),
+		LatencyExecute:     generateLatencyTimings(cb.metrics.DefaultCollector().RunDurationDistribution()),
+		LatencyExecuteMean: cb.metrics.DefaultCollector().RunDurationDistribution().Mean(),
 
 		streamCmdHealthMetric: streamCmdHealthMetric{
 			RequestCount:       uint32(reqCount),
@@ -115,22 +168,27 @@ func (sh *StreamHandler) publishMetrics(cb *CircuitBreaker) error {
 
 			RollingCountSuccess:            uint32(cb.metrics.DefaultCollector().Successes().Sum(now)),
 			RollingCountFailure:            uint32(cb.metrics.DefaultCollector().Failures().Sum(now)),
-			RollingCountThreadPoolRejected: uint32(cb.metrics.DefaultCollector().Rejects().Sum(now)),
+			RollingCountThreadPoolRejected: threadPoolRejects,
+			RollingCountSemaphoreRejected:  semaphoreRejects,
 			RollingCountShortCircuited:     uint32(cb.metrics.DefaultCollector().ShortCircuits().Sum(now)),
 			RollingCountTimeout:            uint32(cb.metrics.DefaultCollector().Timeouts().Sum(now)),
 			RollingCountFallbackSuccess:    uint32(cb.metrics.DefaultCollector().FallbackSuccesses().Sum(now)),
 			RollingCountFallbackFailure:    uint32(cb.metrics.DefaultCollector().FallbackFailures().Sum(now)),
+			RollingCountFallbackRejection:  uint32(cb.metrics.DefaultCollector().FallbackRejections().Sum(now)),
 		},
 		steamCmdPropertiesMetric: steamCmdPropertiesMetric{
 			// TODO: all hard-coded values should become configurable settings, per circuit
-			RollingStatsWindow:                   10000,
-			ExecutionIsolationStrategy:           "THREAD",
-			CircuitBreakerEnabled:                true,
-			CircuitBreakerForceClosed:            false,
-			CircuitBreakerForceOpen:              cb.forceOpen,
-			CircuitBreakerErrorThresholdPercent:  uint32(getSettings(cb.Name).ErrorPercentThreshold),
-			CircuitBreakerSleepWindow:            uint32(getSettings(cb.Name).SleepWindow.Seconds() * 1000),
-			CircuitBreakerRequestVolumeThreshold: uint32(getSettings(cb.Name).RequestVolumeThreshold),
+			RollingStatsWindow:                               uint32(rollingWindow / time.Millisecond),
+			ExecutionIsolationStrategy:                       isolationStrategyName(settings.IsolationStrategy),
+			ExecutionIsolationSemaphoreMaxConcurrentRequests: semaphoreMax,
+			ExecutionIsolationThreadPoolKeyOverride:          settings.ThreadPoolKey,
+			FallbackIsolationSemaphoreMaxConcurrentRequests:  uint32(settings.fallbackMaxConcurrentRequests()),
+			CircuitBreakerEnabled:                            true,
+			CircuitBreakerForceClosed:                        forceClosed,
+			CircuitBreakerForceOpen:                          forceOpen,
+			CircuitBreakerErrorThresholdPercent:              uint32(sh.registry.getSettings(cb.Name).ErrorPercentThreshold),
+			CircuitBreakerSleepWindow:                        uint32(sh.registry.getSettings(cb.Name).SleepWindow.Seconds() * 1000),
+			CircuitBreakerRequestVolumeThreshold:             uint32(sh.registry.getSettings(cb.Name).RequestVolumeThreshold),
 		},
 	})
 	if err != nil {
@@ -144,29 +202,51 @@ func (sh *StreamHandler) publishMetrics(cb *CircuitBreaker) error {
 	return nil
 }
 
-func (sh *StreamHandler) publishThreadPools(pool *bufferedExecutorPool) error {
+// isolationStrategyName returns the name the dashboard knows for the strategy, worker pools are its thread pools.
+func isolationStrategyName(strategy IsolationStrategy) string {
+	if strategy == IsolationSemaphore {
+		return "SEMAPHORE"
+	}
+	return "THREAD"
+}
+
+func (sh *StreamHandler) publishThreadPools(cb *CircuitBreaker) error {
 	now := time.Now()
+	pool := cb.executorPool
+	max, queueSizeRejectionThreshold := pool.Size()
+	// only worker pools count their tasks
+	var tasks, completedTasks uint64
+	if workers := cb.startedWorkerPool(); workers != nil {
+		tasks, completedTasks = workers.TaskCounts()
+	}
+	settings := sh.registry.getSettings(cb.Name)
+	rollingWindow, _ := settings.rollingWindow()
+	// pools with an adaptive limit are sized by it, up to MaxConcurrentRequests
+	maxSize := max
+	if cb.threadPool.adaptive() {
+		maxSize = settings.MaxConcurrentRequests
+	}
 
 	eventBytes, err := json.Marshal(&streamThreadPoolMetric{
 		Type:           "HystrixThreadPool",
 		Name:           pool.Name,
 		ReportingHosts: 1,
 
-		CurrentActiveCount:        uint32(pool.ActiveCount()),
-		CurrentTaskCount:          0,
-		CurrentCompletedTaskCount: 0,
+		CurrentActiveCount:        uint32(cb.ActiveCount()),
+		CurrentTaskCount:          uint32(tasks),
+		CurrentCompletedTaskCount: uint32(completedTasks),
 
 		RollingCountThreadsExecuted: uint32(pool.Metrics.Executed.Sum(now)),
 		RollingMaxActiveThreads:     uint32(pool.Metrics.MaxActiveRequests.Max(now)),
 
-		CurrentPoolSize:        uint32(pool.Max),
-		CurrentCorePoolSize:    uint32(pool.Max),
-		CurrentLargestPoolSize: uint32(pool.Max),
-		CurrentMaximumPoolSize: uint32(pool.Max),
+		CurrentPoolSize:        uint32(max),
+		CurrentCorePoolSize:    uint32(max),
+		CurrentLargestPoolSize: uint32(max),
+		CurrentMaximumPoolSize: uint32(maxSize),
 
-		RollingStatsWindow:          10000,
-		QueueSizeRejectionThreshold: uint32(pool.QueueSizeRejectionThreshold),
-		CurrentQueueSize:            uint32(pool.WaitingCount()),
+		RollingStatsWindow:          uint32(rollingWindow / time.Millisecond),
+		QueueSizeRejectionThreshold: uint32(queueSizeRejectionThreshold),
+		CurrentQueueSize:            uint32(cb.WaitingCount()),
 	})
 	if err != nil {
 		return err
@@ -226,7 +306,7 @@ func (sh *StreamHandler) unregister(req *http.Request) {
 	sh.mu.Unlock()
 }
 
-func generateLatencyTimings(r *rolling.Timing) streamCmdLatency {
+func generateLatencyTimings(r rolling.Distribution) streamCmdLatency {
 	return streamCmdLatency{
 		Timing0:   r.Percentile(0),
 		Timing25:  r.Percentile(25),
@@ -1,6 +1,7 @@
 package hystrix
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -278,3 +279,76 @@ func TestThreadPoolStream(t *testing.T) {
 		})
 	})
 }
+
+func TestStreamHandlerWithoutCloseNotifier(t *testing.T) {
+	Convey("given a running event stream", t, func() {
+		sh := NewStreamHandler()
+		sh.Interval = 10 * time.Millisecond
+		sh.Start()
+		defer sh.Stop()
+		defer Flush()
+
+		sleepingCommand(t, "recorder", 1*time.Millisecond)
+
+		Convey("a response writer without CloseNotify can be served until the request is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			req := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			served := make(chan struct{})
+			go func() {
+				sh.ServeHTTP(rec, req)
+				close(served)
+			}()
+
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			<-served
+
+			So(rec.Header().Get("Content-Type"), ShouldEqual, "text/event-stream")
+			So(rec.Body.String(), ShouldContainSubstring, `"name":"recorder"`)
+		})
+	})
+}
+
+func TestStopDisconnectsClients(t *testing.T) {
+	Convey("given a running event stream with a connected client", t, func() {
+		sh := NewStreamHandler()
+		sh.Start()
+		defer Flush()
+
+		req := httptest.NewRequest("GET", "/", nil)
+		served := make(chan struct{})
+		go func() {
+			sh.ServeHTTP(httptest.NewRecorder(), req)
+			close(served)
+		}()
+
+		Convey("stopping the handler disconnects the client", func() {
+			sh.Stop()
+			select {
+			case <-served:
+			case <-time.After(time.Second):
+				t.Fatal("client was not disconnected")
+			}
+		})
+	})
+}
+
+func TestStreamCommandGroup(t *testing.T) {
+	Convey("given a running event stream", t, func() {
+		server := startTestServer()
+		defer func() {
+			_ = server.stopTestServer()
+		}()
+
+		Convey("after a command of a group runs", func() {
+			ConfigureCommand("grouped", CommandConfig{CommandGroup: "group"})
+			sleepingCommand(t, "grouped", 1*time.Millisecond)
+
+			Convey("the group of the command is reported", func() {
+				metric := grabFirstCommandFromStream(t, server.URL)
+				So(metric.Group, ShouldEqual, "group")
+			})
+		})
+	})
+}
@@ -0,0 +1,100 @@
+package hystrix
+
+import (
+	"context"
+	"sync"
+)
+
+// A Future holds the result of a command started with ExecuteAsync.
+type Future[T any] struct {
+	once  sync.Once
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// Execute runs your function as a command in a synchronous manner and returns its value.
+// When run fails, times out or is rejected by the circuit, the value and error returned by
+// fallback are used instead. Without a fallback the zero value is returned with the error.
+func Execute[T any](ctx context.Context, name string, run func(context.Context) (T, error), fallback func(context.Context, error) (T, error)) (T, error) {
+	return ExecuteAsyncOn(defaultRegistry, ctx, name, run, fallback).Get()
+}
+
+// ExecuteOn is like Execute but runs the command on a circuit of the given registry.
+func ExecuteOn[T any](registry *Registry, ctx context.Context, name string, run func(context.Context) (T, error), fallback func(context.Context, error) (T, error)) (T, error) {
+	return ExecuteAsyncOn(registry, ctx, name, run, fallback).Get()
+}
+
+// ExecuteAsync runs your function as a command without waiting for it to finish.
+// The returned Future resolves with the value of run, or of fallback, exactly like Execute.
+func ExecuteAsync[T any](ctx context.Context, name string, run func(context.Context) (T, error), fallback func(context.Context, error) (T, error)) *Future[T] {
+	return ExecuteAsyncOn(defaultRegistry, ctx, name, run, fallback)
+}
+
+// ExecuteAsyncOn is like ExecuteAsync but runs the command on a circuit of the given registry.
+func ExecuteAsyncOn[T any](registry *Registry, ctx context.Context, name string, run func(context.Context) (T, error), fallback func(context.Context, error) (T, error)) *Future[T] {
+	f := &Future[T]{
+		done: make(chan struct{}),
+	}
+
+	runC := func(ctx context.Context) error {
+		v, err := run(ctx)
+		if err != nil {
+			return err
+		}
+		// the command has already timed out or was abandoned by the caller,
+		// so the value must not win over the fallback
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		f.resolve(v, nil)
+		return nil
+	}
+
+	var fallbackC fallbackFuncC
+	if fallback != nil {
+		fallbackC = func(ctx context.Context, err error) error {
+			v, fallbackErr := fallback(ctx, err)
+			if fallbackErr != nil {
+				return fallbackErr
+			}
+
+			f.resolve(v, nil)
+			return nil
+		}
+	}
+
+	errChan := registry.GoC(ctx, name, runC, fallbackC)
+	go func() {
+		select {
+		case err := <-errChan:
+			var zero T
+			f.resolve(zero, err)
+		case <-f.done:
+		}
+	}()
+
+	return f
+}
+
+// Get blocks until the command has finished and returns its result.
+func (f *Future[T]) Get() (T, error) {
+	<-f.done
+	return f.value, f.err
+}
+
+// Done returns a channel which is closed once the result of the command is available.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// resolve records the first result of the command, a run which returns after the
+// fallback has already completed is ignored.
+func (f *Future[T]) resolve(value T, err error) {
+	f.once.Do(func() {
+		f.value = value
+		f.err = err
+		close(f.done)
+	})
+}
@@ -0,0 +1,87 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExecute(t *testing.T) {
+	Convey("with a typed command which succeeds", t, func() {
+		defer Flush()
+
+		v, err := Execute(context.Background(), "", func(ctx context.Context) (int, error) {
+			return 1, nil
+		}, nil)
+
+		Convey("the value of run is returned", func() {
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 1)
+		})
+	})
+
+	Convey("with a typed command which fails", t, func() {
+		defer Flush()
+
+		run := func(ctx context.Context) (string, error) {
+			return "", fmt.Errorf("i failed")
+		}
+
+		Convey("with no fallback", func() {
+			v, err := Execute(context.Background(), "", run, nil)
+
+			Convey("the zero value and the error are returned", func() {
+				So(v, ShouldEqual, "")
+				So(err.Error(), ShouldEqual, "i failed")
+			})
+		})
+
+		Convey("with a succeeding fallback", func() {
+			v, err := Execute(context.Background(), "", run, func(ctx context.Context, err error) (string, error) {
+				return "fallback", nil
+			})
+
+			Convey("the value of the fallback is returned", func() {
+				So(err, ShouldBeNil)
+				So(v, ShouldEqual, "fallback")
+			})
+		})
+
+		Convey("with a failing fallback", func() {
+			_, err := Execute(context.Background(), "", run, func(ctx context.Context, err error) (string, error) {
+				return "", fmt.Errorf("fallback failed")
+			})
+
+			Convey("both errors are returned", func() {
+				So(err.Error(), ShouldEqual, "fallback failed with 'fallback failed'. run error was 'i failed'")
+			})
+		})
+	})
+}
+
+func TestExecuteAsync(t *testing.T) {
+	Convey("with a typed command which times out", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{Timeout: 10})
+
+		f := ExecuteAsync(context.Background(), "", func(ctx context.Context) (int, error) {
+			<-ctx.Done()
+			return 1, nil
+		}, nil)
+
+		Convey("the future resolves with a timeout error", func() {
+			select {
+			case <-f.Done():
+			case <-time.After(time.Second):
+				t.Fatal("future was not resolved")
+			}
+
+			v, err := f.Get()
+			So(v, ShouldEqual, 0)
+			So(err, ShouldResemble, ErrTimeout)
+		})
+	})
+}
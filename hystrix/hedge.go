@@ -0,0 +1,86 @@
+package hystrix
+
+import (
+	"context"
+	"time"
+)
+
+// HedgePolicy starts a second attempt of a command whose run function has not returned after a delay,
+// to cut its tail latency. The first attempt to return wins, the context of the other is canceled.
+//
+// The hedged attempt needs an execution slot of its own, taken from the executor pool, or the worker
+// pool with IsolationWorkerPool, without waiting. No attempt is hedged when the pool is full.
+// Run functions must therefore be safe to call twice at the same time, as for idempotent reads.
+type HedgePolicy struct {
+	// Delay after which the second attempt is started.
+	Delay time.Duration
+	// Percentile of the recent run durations of the circuit after which the second attempt is started
+	// instead of Delay, e.g. 95. Delay is used until the circuit has run any command.
+	Percentile float64
+}
+
+// delay returns how long to wait for the first attempt of a command of circuit before hedging it,
+// hedging is disabled when it is not positive.
+func (p *HedgePolicy) delay(circuit *CircuitBreaker) time.Duration {
+	if p.Percentile > 0 {
+		if d := circuit.Metrics().RunDurationDistribution().PercentileDuration(p.Percentile); d > 0 {
+			return d
+		}
+	}
+	return p.Delay
+}
+
+// runHedged calls run, and calls it a second time when the first attempt has not returned after delay.
+// It returns the result of whichever attempt returns first.
+func (c *command) runHedged(ctx context.Context, run runFuncC, delay time.Duration) error {
+	hedgeCtx, cancel := context.WithCancel(ctx)
+	// cancels the attempt which lost
+	defer cancel()
+
+	results := make(chan error, 2)
+	go func() {
+		results <- run(hedgeCtx)
+	}()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case err := <-results:
+		return err
+	case <-timer.C:
+	}
+
+	hedge := func() {
+		// attempts queued on a worker pool may start after the race is over
+		if hedgeCtx.Err() != nil {
+			return
+		}
+		results <- run(hedgeCtx)
+	}
+	if c.startHedge(hedge) {
+		c.reportEvent(EventHedged)
+	}
+
+	return <-results
+}
+
+// startHedge runs a hedged attempt on an execution slot of its own, and returns false when none is free.
+func (c *command) startHedge(attempt func()) bool {
+	if c.circuit.settings().IsolationStrategy == IsolationWorkerPool {
+		// a queued hedge would start no sooner than the attempt it is meant to overtake
+		return c.circuit.workerPool().TrySubmit(attempt)
+	}
+
+	pool := c.circuit.executorPool
+	tickets, _ := pool.tickets()
+	select {
+	case ticket := <-tickets:
+		go func() {
+			defer pool.Return(ticket)
+			attempt()
+		}()
+		return true
+	default:
+		return false
+	}
+}
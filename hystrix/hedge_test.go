@@ -0,0 +1,116 @@
+package hystrix
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHedgePolicy(t *testing.T) {
+	Convey("given a circuit hedging commands slower than 20ms", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("hedged", CommandConfig{Timeout: 500, MaxConcurrentRequests: 2})
+		registry.UpdateSettings("hedged", func(s *Settings) {
+			s.HedgePolicy = &HedgePolicy{Delay: 20 * time.Millisecond}
+		})
+		cb, _, _ := registry.GetCircuit("hedged")
+		log := &callLog{done: make(chan []EventType, 10)}
+		registry.AddInterceptor(&recordingInterceptor{name: "audit", log: log})
+
+		var attempts int32
+		canceled := make(chan struct{})
+		// the first attempt hangs until it is canceled, the second returns at once
+		slowFirst := func(ctx context.Context) error {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				<-ctx.Done()
+				close(canceled)
+				return ctx.Err()
+			}
+			return nil
+		}
+
+		Convey("a fast command is not hedged", func() {
+			So(registry.DoC(context.Background(), "hedged", func(ctx context.Context) error {
+				atomic.AddInt32(&attempts, 1)
+				return nil
+			}, nil), ShouldBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+			So(<-log.done, ShouldResemble, []EventType{EventSuccess})
+		})
+
+		Convey("a slow command is hedged and the first result wins", func() {
+			start := time.Now()
+			So(registry.DoC(context.Background(), "hedged", slowFirst, nil), ShouldBeNil)
+			So(time.Since(start), ShouldBeLessThan, 500*time.Millisecond)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 2)
+			So(<-log.done, ShouldResemble, []EventType{EventHedged, EventSuccess})
+
+			Convey("and the losing attempt is canceled", func() {
+				<-canceled
+			})
+
+			Convey("and counted as hedged", func() {
+				for cb.Metrics().Hedges().Sum(time.Now()) < 1 {
+					time.Sleep(time.Millisecond)
+				}
+				So(cb.Metrics().Hedges().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("no attempt is hedged without a free execution slot", func() {
+			registry.UpdateSettings("hedged", func(s *Settings) { s.MaxConcurrentRequests = 1 })
+			err := registry.DoC(context.Background(), "hedged", func(ctx context.Context) error {
+				atomic.AddInt32(&attempts, 1)
+				time.Sleep(40 * time.Millisecond)
+				return nil
+			}, nil)
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+			So(<-log.done, ShouldResemble, []EventType{EventSuccess})
+		})
+
+		Convey("worker pools hedge on a worker of their own", func() {
+			registry.UpdateSettings("hedged", func(s *Settings) { s.IsolationStrategy = IsolationWorkerPool })
+			So(registry.DoC(context.Background(), "hedged", slowFirst, nil), ShouldBeNil)
+			So(<-log.done, ShouldResemble, []EventType{EventHedged, EventSuccess})
+			<-canceled
+		})
+
+		Convey("worker pools do not queue hedges", func() {
+			registry.UpdateSettings("hedged", func(s *Settings) {
+				s.IsolationStrategy = IsolationWorkerPool
+				s.MaxConcurrentRequests = 1
+			})
+			err := registry.DoC(context.Background(), "hedged", func(ctx context.Context) error {
+				atomic.AddInt32(&attempts, 1)
+				time.Sleep(40 * time.Millisecond)
+				return nil
+			}, nil)
+			So(err, ShouldBeNil)
+			So(atomic.LoadInt32(&attempts), ShouldEqual, 1)
+			So(<-log.done, ShouldResemble, []EventType{EventSuccess})
+		})
+	})
+}
+
+func TestHedgeDelay(t *testing.T) {
+	Convey("given a hedge policy using the 90th percentile of run durations", t, func() {
+		registry := NewRegistry()
+		cb, _, _ := registry.GetCircuit("percentile")
+		policy := &HedgePolicy{Delay: 50 * time.Millisecond, Percentile: 90}
+
+		Convey("the delay is used until commands ran", func() {
+			So(policy.delay(cb), ShouldEqual, 50*time.Millisecond)
+		})
+
+		Convey("the percentile is used once they did", func() {
+			for i := 1; i <= 10; i++ {
+				cb.Metrics().UpdateRunDuration(time.Duration(i) * time.Millisecond)
+			}
+			So(policy.delay(cb), ShouldBeBetweenOrEqual, 9*time.Millisecond, 10*time.Millisecond)
+		})
+	})
+}
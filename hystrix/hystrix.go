@@ -1,6 +1,7 @@
 package hystrix
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"sync"
@@ -9,6 +10,8 @@ import (
 
 type runFunc func() error
 type fallbackFunc func(error) error
+type runFuncC func(context.Context) error
+type fallbackFuncC func(context.Context, error) error
 
 // A CircuitError is an error which models various failure states of execution,
 // such as the circuit being open or a timeout.
@@ -33,12 +36,21 @@ type command struct {
 	timeoutChan    chan struct{}
 	fallbackOnce   *sync.Once
 	circuit        *CircuitBreaker
-	run            runFunc
-	fallback       fallbackFunc
+	run            runFuncC
+	fallback       fallbackFuncC
+	cancelRun      context.CancelFunc
 	runDuration    time.Duration
-	events         []string
+	queueWait      time.Duration
+	events         []EventType
+	err            error
 	timedOut       bool
 	ticketChecked  chan struct{}
+	execution      *Execution
+	interceptors   interceptors
+	// the round of the half-open circuit the command is a trial of, zero when it is not a trial
+	trialRound int
+	// canceled is set once the watcher of a command started by GoC gave up on it because its context ended
+	canceled bool
 }
 
 var (
@@ -48,6 +60,10 @@ var (
 	ErrCircuitOpen = CircuitError{Message: "circuit open"}
 	// ErrTimeout occurs when the provided function takes too long to execute.
 	ErrTimeout = CircuitError{Message: "timeout"}
+	// ErrFallbackRejected occurs when the fallback could not run because too many fallbacks of the same named command were running.
+	ErrFallbackRejected = CircuitError{Message: "fallback rejected"}
+	// ErrRetryBudgetExhausted occurs, wrapped together with the error of the failed attempt, when a failed command could not be retried because its retry budget was used up.
+	ErrRetryBudgetExhausted = CircuitError{Message: "retry budget exhausted"}
 )
 
 // Go runs your function while tracking the health of previous calls to it.
@@ -56,27 +72,63 @@ var (
 //
 // Define a fallback function if you want to define some code to execute during outages.
 func Go(name string, run runFunc, fallback fallbackFunc) chan error {
-	cmd := &command{
-		run:           run,
-		fallback:      fallback,
-		start:         time.Now(),
-		errChan:       make(chan error, 1),
-		finished:      make(chan bool, 1),
-		fallbackOnce:  &sync.Once{},
-		timeoutChan:   make(chan struct{}, 1),
-		ticketChecked: make(chan struct{}),
+	return defaultRegistry.Go(name, run, fallback)
+}
+
+// Go runs your function as a command on a circuit of this registry, see Go.
+func (r *Registry) Go(name string, run runFunc, fallback fallbackFunc) chan error {
+	runC := func(ctx context.Context) error {
+		return run()
+	}
+	var fallbackC fallbackFuncC
+	if fallback != nil {
+		fallbackC = func(ctx context.Context, err error) error {
+			return fallback(err)
+		}
+	}
+	return r.GoC(context.Background(), name, runC, fallbackC)
+}
+
+// GoC runs your function while tracking the health of previous calls to it.
+// If your function begins slowing down or failing repeatedly, we will block
+// new calls to it for you to give the dependent service time to repair.
+//
+// The context passed to run is derived from ctx and is cancelled once the command
+// times out, so run should return as soon as it observes ctx.Done(). Cancellation of
+// ctx by the caller stops the command and is reported as a context event rather than a failure.
+//
+// Define a fallback function if you want to define some code to execute during outages.
+func GoC(ctx context.Context, name string, run runFuncC, fallback fallbackFuncC) chan error {
+	return defaultRegistry.GoC(ctx, name, run, fallback)
+}
+
+// GoC runs your function as a command on a circuit of this registry, see GoC.
+func (r *Registry) GoC(ctx context.Context, name string, run runFuncC, fallback fallbackFuncC) chan error {
+	circuit, _, err := r.GetCircuit(name)
+	if err != nil {
+		errChan := make(chan error, 1)
+		errChan <- err
+		return errChan
 	}
+	return r.goC(ctx, circuit, run, fallback)
+}
 
+// goC runs run as a command on circuit, see GoC.
+func (r *Registry) goC(ctx context.Context, circuit *CircuitBreaker, run runFuncC, fallback fallbackFuncC) chan error {
 	// dont have methods with explicit params and returns
 	// let data come in and out naturally, like with any closure
 	// explicit error return to give place for us to kill switch the operation (fallback)
 
-	circuit, _, err := GetCircuit(name)
-	if err != nil {
-		cmd.errChan <- err
+	cmd := r.newCommand(circuit, run, fallback)
+
+	if strategy := circuit.settings().IsolationStrategy; strategy != IsolationThread {
+		go cmd.execute(ctx, strategy)
 		return cmd.errChan
 	}
-	cmd.circuit = circuit
+
+	ctx = cmd.interceptors.beforeAcquire(ctx, cmd.execution)
+	runCtx, cancelRun := context.WithCancel(ctx)
+	cmd.cancelRun = cancelRun
 
 	go func() {
 		defer func() {
@@ -86,8 +138,11 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 		// Circuits get opened when recent executions have shown to have a high error rate.
 		// Rejecting new executions allows backends to recover, and the circuit will allow
 		// new traffic when it feels a healthly state has returned.
-		if !cmd.circuit.AllowRequest() {
-			cmd.errorWithFallback(ErrCircuitOpen)
+		allowed, trialRound := cmd.circuit.allowRequest()
+		cmd.trialRound = trialRound
+		if !allowed {
+			cmd.interceptors.afterAcquire(ctx, cmd.execution, 0, ErrCircuitOpen)
+			cmd.errorWithFallback(ctx, ErrCircuitOpen)
 			close(cmd.ticketChecked)
 			return
 		}
@@ -98,43 +153,59 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 		// run more at a time to keep up. By controlling concurrency during these situations, you can
 		// shed load which accumulates due to the increasing ratio of active commands to incoming requests.
 
+		tickets, _ := circuit.executorPool.tickets()
 		select {
-		case t := <-circuit.executorPool.Tickets:
+		case t := <-tickets:
 			cmd.setTicket(t)
+			cmd.interceptors.afterAcquire(ctx, cmd.execution, 0, nil)
 
 		default:
 			select {
-			case t := <-circuit.executorPool.WaitingTicket:
-				cmd.reportEvent("queued")
+			case t := <-circuit.executorPool.waitingTickets():
+				cmd.reportEvent(EventQueued)
 				cmd.setOverflowTicket(t)
 			default: // Unable to get execution or waiting ticket, error with MaxConcurrency
-				cmd.errorWithFallback(ErrMaxConcurrency)
+				cmd.interceptors.afterAcquire(ctx, cmd.execution, 0, ErrMaxConcurrency)
+				cmd.errorWithFallback(ctx, ErrMaxConcurrency)
 				close(cmd.ticketChecked)
 				return
 			}
 
 			// Unable to execute the cmd but was able to get the waiting slot
-			select {
-			case executionTicket := <-circuit.executorPool.Tickets:
-				// return the ticket right away as it is not required
-				cmd.circuit.executorPool.ReturnWaitingTicket(cmd.overflowTicket)
-				cmd.setTicket(executionTicket)
-				if circuit.IsOpen() {
-					cmd.errorWithFallback(ErrCircuitOpen)
-					close(cmd.ticketChecked)
-					return
+			waitStart := time.Now()
+			executionTicket := circuit.executorPool.WaitTicket(cmd.timeoutChan, ctx.Done())
+			wait := time.Since(waitStart)
+			cmd.setQueueWait(wait)
+			// return the ticket right away as it is not required
+			cmd.circuit.executorPool.ReturnWaitingTicket(cmd.overflowTicket)
+			if executionTicket == nil {
+				if ctx.Err() != nil {
+					// the caller gave up while the command was waiting in queue
+					cmd.interceptors.afterAcquire(ctx, cmd.execution, wait, ctx.Err())
+					cmd.errorWithFallback(ctx, ctx.Err())
+				} else {
+					cmd.interceptors.afterAcquire(ctx, cmd.execution, wait, ErrMaxConcurrency)
 				}
-			case <-cmd.timeoutChan:
-				// return the ticket right away as it is not required
-				cmd.circuit.executorPool.ReturnWaitingTicket(cmd.overflowTicket)
 				close(cmd.ticketChecked)
 				return
 			}
+
+			cmd.setTicket(executionTicket)
+			// trials have to run for a half-open circuit to ever close
+			if cmd.trialRound == 0 && circuit.IsOpen() {
+				cmd.interceptors.afterAcquire(ctx, cmd.execution, wait, ErrCircuitOpen)
+				cmd.errorWithFallback(ctx, ErrCircuitOpen)
+				close(cmd.ticketChecked)
+				return
+			}
+			cmd.interceptors.afterAcquire(ctx, cmd.execution, wait, nil)
 		}
 
 		close(cmd.ticketChecked)
+		runCtx := cmd.interceptors.beforeRun(runCtx, cmd.execution)
 		runStart := time.Now()
-		runErr := run()
+		runErr := cmd.run(runCtx)
+		cmd.interceptors.afterRun(runCtx, cmd.execution, time.Since(runStart), runErr)
 
 		if cmd.isTimedOut() {
 			return
@@ -142,49 +213,54 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 
 		cmd.setRunDuration(time.Since(runStart))
 
+		// report commands abandoned by their caller the same way the watcher below does,
+		// whichever of us gets there first, even when run ignored its context and succeeded
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			runErr = ctxErr
+		}
 		if runErr != nil {
-			cmd.errorWithFallback(runErr)
+			cmd.errorWithFallback(ctx, runErr)
 			return
 		}
 
-		cmd.reportEvent("success")
+		cmd.reportSuccess()
 	}()
 
 	go func() {
 		defer func() {
+			cmd.cancelRun()
 			<-cmd.ticketChecked
-
-			cmd.mu.Lock()
-			cmd.circuit.executorPool.Return(cmd.ticket)
-			copyEvents := append([]string(nil), cmd.events...)
-			cmd.mu.Unlock()
-
-			err := cmd.circuit.ReportEvent(copyEvents, cmd.start, cmd.getRunDuration())
-			if err != nil {
-				log.Print(err)
-			}
+			cmd.report(ctx)
 		}()
 
-		timer := time.NewTimer(getSettings(name).Timeout)
+		timer := time.NewTimer(circuit.settings().Timeout)
 		defer timer.Stop()
 
 		select {
 		case <-cmd.finished:
+		case <-ctx.Done():
+			cmd.cancelRun()
+			if cmd.markCanceled() {
+				cmd.errorWithFallback(ctx, ctx.Err())
+			}
 		case <-timer.C:
 			close(cmd.timeoutChan)
+			cmd.mu.Lock()
+			cmd.timedOut = true
+			cmd.mu.Unlock()
+			// stop the run function before spending any time in the fallback
+			cmd.cancelRun()
+
 			// mark as timeout only if the reason is timeout,
 			// if the job was in overflowQueue mark it as MaxConcurrency
 			if cmd.hasOverflowTicket() {
 				// even if the execution was waiting in queue and then timed-out while executing,
 				// mark it as ErrMaxConcurrency
-				cmd.errorWithFallback(ErrMaxConcurrency)
+				cmd.errorWithFallback(ctx, ErrMaxConcurrency)
 				return
 			}
 
-			cmd.mu.Lock()
-			cmd.timedOut = true
-			cmd.mu.Unlock()
-			cmd.errorWithFallback(ErrTimeout)
+			cmd.errorWithFallback(ctx, ErrTimeout)
 		}
 	}()
 
@@ -194,10 +270,56 @@ func Go(name string, run runFunc, fallback fallbackFunc) chan error {
 // Do runs your function in a synchronous manner, blocking until either your function succeeds
 // or an error is returned, including hystrix circuit errors
 func Do(name string, run runFunc, fallback fallbackFunc) error {
+	return defaultRegistry.Do(name, run, fallback)
+}
+
+// Do runs your function as a command on a circuit of this registry, see Do.
+func (r *Registry) Do(name string, run runFunc, fallback fallbackFunc) error {
+	runC := func(ctx context.Context) error {
+		return run()
+	}
+	var fallbackC fallbackFuncC
+	if fallback != nil {
+		fallbackC = func(ctx context.Context, err error) error {
+			return fallback(err)
+		}
+	}
+	return r.DoC(context.Background(), name, runC, fallbackC)
+}
+
+// DoC runs your function in a synchronous manner, blocking until either your function succeeds
+// or an error is returned, including hystrix circuit errors and errors of ctx.
+func DoC(ctx context.Context, name string, run runFuncC, fallback fallbackFuncC) error {
+	return defaultRegistry.DoC(ctx, name, run, fallback)
+}
+
+// DoC runs your function as a command on a circuit of this registry, see DoC.
+func (r *Registry) DoC(ctx context.Context, name string, run runFuncC, fallback fallbackFuncC) error {
+	circuit, _, err := r.GetCircuit(name)
+	if err != nil {
+		return err
+	}
+	return r.doC(ctx, circuit, run, fallback)
+}
+
+// doC runs run as a command on circuit and waits for it, see DoC.
+func (r *Registry) doC(ctx context.Context, circuit *CircuitBreaker, run runFuncC, fallback fallbackFuncC) error {
+	if strategy := circuit.settings().IsolationStrategy; strategy != IsolationThread {
+		cmd := r.newCommand(circuit, run, fallback)
+		cmd.execute(ctx, strategy)
+
+		select {
+		case err := <-cmd.errChan:
+			return err
+		default:
+			return nil
+		}
+	}
+
 	done := make(chan struct{}, 1)
 
-	r := func() error {
-		err := run()
+	rn := func(ctx context.Context) error {
+		err := run(ctx)
 		if err != nil {
 			return err
 		}
@@ -206,8 +328,8 @@ func Do(name string, run runFunc, fallback fallbackFunc) error {
 		return nil
 	}
 
-	f := func(e error) error {
-		err := fallback(e)
+	f := func(ctx context.Context, e error) error {
+		err := fallback(ctx, e)
 		if err != nil {
 			return err
 		}
@@ -218,9 +340,9 @@ func Do(name string, run runFunc, fallback fallbackFunc) error {
 
 	var errChan chan error
 	if fallback == nil {
-		errChan = Go(name, r, nil)
+		errChan = r.goC(ctx, circuit, rn, nil)
 	} else {
-		errChan = Go(name, r, f)
+		errChan = r.goC(ctx, circuit, rn, f)
 	}
 
 	select {
@@ -231,7 +353,74 @@ func Do(name string, run runFunc, fallback fallbackFunc) error {
 	}
 }
 
-func (c *command) reportEvent(eventType string) {
+// newCommand prepares an execution of run on circuit.
+func (r *Registry) newCommand(circuit *CircuitBreaker, run runFuncC, fallback fallbackFuncC) *command {
+	cmd := &command{
+		run:           run,
+		fallback:      fallback,
+		start:         time.Now(),
+		errChan:       make(chan error, 1),
+		finished:      make(chan bool, 1),
+		fallbackOnce:  &sync.Once{},
+		timeoutChan:   make(chan struct{}, 1),
+		ticketChecked: make(chan struct{}),
+		circuit:       circuit,
+		interceptors:  r.getInterceptors(circuit.command),
+	}
+	cmd.execution = &Execution{Name: circuit.command, Key: circuit.Key, CommandGroup: circuit.CommandGroup, Start: cmd.start}
+
+	settings := circuit.settings()
+	if policy := settings.HedgePolicy; policy != nil {
+		if delay := policy.delay(circuit); delay > 0 {
+			run := cmd.run
+			cmd.run = func(ctx context.Context) error {
+				return cmd.runHedged(ctx, run, delay)
+			}
+		}
+	}
+	if policy := settings.RetryPolicy; policy != nil && policy.MaxAttempts > 1 {
+		budget := r.getRetryBudget(settings)
+		deadline := cmd.start.Add(settings.Timeout)
+		run := cmd.run
+		cmd.run = func(ctx context.Context) error {
+			return cmd.runWithRetries(ctx, run, policy, budget, deadline)
+		}
+	}
+
+	return cmd
+}
+
+// report returns the execution ticket of the command and reports its result to the circuit and the interceptors.
+func (c *command) report(ctx context.Context) {
+	c.mu.Lock()
+	ticket := c.ticket
+	result := &ExecutionResult{
+		Events:        append([]EventType(nil), c.events...),
+		Start:         c.start,
+		QueueWait:     c.queueWait,
+		RunDuration:   c.runDuration,
+		TotalDuration: time.Since(c.start),
+		Err:           c.err,
+	}
+	c.mu.Unlock()
+
+	// the adaptive limit of the pool learns from the commands which ran, before they stop counting as running
+	if result.HasEvent(EventSuccess) || result.HasEvent(EventFailure) || result.HasEvent(EventTimeout) {
+		c.circuit.threadPool.observe(result.RunDuration, !result.HasEvent(EventSuccess))
+	}
+	c.circuit.executorPool.Return(ticket)
+
+	if c.trialRound != 0 {
+		c.circuit.reportTrial(result, c.trialRound)
+	}
+	err := c.circuit.reportResult(result)
+	if err != nil {
+		log.Print(err)
+	}
+	c.interceptors.done(ctx, c.execution, result)
+}
+
+func (c *command) reportEvent(eventType EventType) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -245,41 +434,83 @@ func (c *command) isTimedOut() bool {
 	return c.timedOut
 }
 
+// reportSuccess records EventSuccess unless the watcher already gave up on the command because it timed out
+// or its context ended, in which case the watcher reports it.
+func (c *command) reportSuccess() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.timedOut || c.canceled {
+		return
+	}
+	c.events = append(c.events, EventSuccess)
+}
+
+// markCanceled records that the watcher gives up on the command because its context ended, unless the command
+// succeeded first, and returns whether it did.
+func (c *command) markCanceled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, event := range c.events {
+		if event == EventSuccess {
+			return false
+		}
+	}
+	c.canceled = true
+	return true
+}
+
 // errorWithFallback triggers the fallback while reporting the appropriate metric events.
 // If called multiple times for a single command, only the first will execute to insure
 // accurate metrics and prevent the fallback from executing more than once.
-func (c *command) errorWithFallback(err error) {
+func (c *command) errorWithFallback(ctx context.Context, err error) {
 	c.fallbackOnce.Do(func() {
-		eventType := "failure"
+		eventType := EventFailure
 		if err == ErrCircuitOpen {
-			eventType = "short-circuit"
+			eventType = EventShortCircuit
 		} else if err == ErrMaxConcurrency {
-			eventType = "rejected"
+			eventType = EventRejected
 		} else if err == ErrTimeout {
-			eventType = "timeout"
+			eventType = EventTimeout
+		} else if err == context.Canceled {
+			eventType = EventContextCanceled
+		} else if err == context.DeadlineExceeded {
+			eventType = EventContextDeadlineExceeded
 		}
 
 		c.reportEvent(eventType)
-		fallbackErr := c.tryFallback(err)
+		fallbackErr := c.tryFallback(ctx, err)
 		if fallbackErr != nil {
+			c.mu.Lock()
+			c.err = fallbackErr
+			c.mu.Unlock()
 			c.errChan <- fallbackErr
 		}
 	})
 }
 
-func (c *command) tryFallback(err error) error {
+func (c *command) tryFallback(ctx context.Context, err error) error {
 	if c.fallback == nil {
 		// If we don't have a fallback return the original error.
 		return err
 	}
 
-	fallbackErr := c.fallback(err)
+	if !c.circuit.acquireFallback() {
+		c.reportEvent(EventFallbackRejection)
+		return fmt.Errorf("fallback failed with '%v'. run error was '%v'", ErrFallbackRejected, err)
+	}
+	defer c.circuit.releaseFallback()
+
+	ctx = c.interceptors.beforeFallback(ctx, c.execution, err)
+	fallbackErr := c.fallback(ctx, err)
+	c.interceptors.afterFallback(ctx, c.execution, fallbackErr)
 	if fallbackErr != nil {
-		c.reportEvent("fallback-failure")
+		c.reportEvent(EventFallbackFailure)
 		return fmt.Errorf("fallback failed with '%v'. run error was '%v'", fallbackErr, err)
 	}
 
-	c.reportEvent("fallback-success")
+	c.reportEvent(EventFallbackSuccess)
 
 	return nil
 }
@@ -305,16 +536,16 @@ func (c *command) setOverflowTicket(t *struct{}) {
 	c.overflowTicket = t
 }
 
-func (c *command) setRunDuration(duration time.Duration) {
+func (c *command) setQueueWait(wait time.Duration) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.runDuration = duration
+	c.queueWait = wait
 }
 
-func (c *command) getRunDuration() time.Duration {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+func (c *command) setRunDuration(duration time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return c.runDuration
+	c.runDuration = duration
 }
@@ -36,3 +36,29 @@ func BenchmarkGo(b *testing.B) {
 	}
 	_ = pprof.Lookup("goroutine").WriteTo(os.Stdout, 1)
 }
+
+func benchmarkDo(b *testing.B, strategy string) {
+	registry := NewRegistry()
+	registry.ConfigureCommand("bench-command", CommandConfig{
+		MaxConcurrentRequests: 1000,
+		IsolationStrategy:     strategy,
+	})
+	// warm the circuit up before measuring
+	_ = registry.Do("bench-command", func() error { return nil }, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = registry.Do("bench-command", func() error { return nil }, nil)
+		}
+	})
+}
+
+func BenchmarkDoThreadIsolation(b *testing.B) {
+	benchmarkDo(b, "thread")
+}
+
+func BenchmarkDoSemaphoreIsolation(b *testing.B) {
+	benchmarkDo(b, "semaphore")
+}
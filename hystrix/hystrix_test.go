@@ -1,6 +1,7 @@
 package hystrix
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -159,11 +160,13 @@ func TestMaxConcurrent(t *testing.T) {
 func TestForceOpenCircuit(t *testing.T) {
 	Convey("when a command with a forced open circuit is run", t, func() {
 		defer Flush()
+		// forcing is stored in the settings, which outlive the circuit
+		defer ClearForce("")
 
 		cb, _, err := GetCircuit("")
 		So(err, ShouldEqual, nil)
 
-		_ = cb.toggleForceOpen(true)
+		cb.ForceOpen()
 
 		errChan := Go("", func() error {
 			return nil
@@ -214,6 +217,55 @@ func TestFailedFallback(t *testing.T) {
 	})
 }
 
+func TestFallbackRejected(t *testing.T) {
+	Convey("with a circuit allowing a single fallback at a time, whose fallback is running", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("fallback", CommandConfig{FallbackMaxConcurrentRequests: 1})
+		cb, _, _ := registry.GetCircuit("fallback")
+
+		block := make(chan struct{})
+		started := make(chan struct{})
+		registry.Go("fallback", func() error {
+			return fmt.Errorf("run_error")
+		}, func(err error) error {
+			close(started)
+			<-block
+			return nil
+		})
+		<-started
+
+		Convey("a second fallback is rejected without running", func() {
+			ran := false
+			err := registry.Do("fallback", func() error {
+				return fmt.Errorf("run_error")
+			}, func(err error) error {
+				ran = true
+				return nil
+			})
+			close(block)
+
+			So(err.Error(), ShouldEqual, "fallback failed with 'hystrix: fallback rejected'. run error was 'run_error'")
+			So(ran, ShouldBeFalse)
+			for cb.Metrics().FallbackRejections().Sum(time.Now()) < 1 {
+				time.Sleep(time.Millisecond)
+			}
+			So(cb.Metrics().FallbackRejections().Sum(time.Now()), ShouldEqual, 1)
+		})
+
+		Convey("the slot is free again once the first fallback returns", func() {
+			close(block)
+			for atomic.LoadInt32(&cb.fallbacksRunning) > 0 {
+				time.Sleep(time.Millisecond)
+			}
+			So(registry.Do("fallback", func() error {
+				return fmt.Errorf("run_error")
+			}, func(err error) error {
+				return nil
+			}), ShouldBeNil)
+		})
+	})
+}
+
 func TestCloseCircuitAfterSuccess(t *testing.T) {
 	Convey("when a circuit is open", t, func() {
 		defer Flush()
@@ -700,3 +752,117 @@ func TestSuccessExecutionDueToQueue(t *testing.T) {
 		})
 	})
 }
+
+func TestGoCTimeoutCancelsRun(t *testing.T) {
+	Convey("with a context-aware command which times out", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{Timeout: 50})
+
+		cancelled := make(chan error, 1)
+		errChan := GoC(context.Background(), "", func(ctx context.Context) error {
+			<-ctx.Done()
+			cancelled <- ctx.Err()
+			return ctx.Err()
+		}, nil)
+
+		Convey("a timeout error is returned", func() {
+			So(<-errChan, ShouldResemble, ErrTimeout)
+
+			Convey("and the context given to run is cancelled", func() {
+				So(<-cancelled, ShouldEqual, context.Canceled)
+
+				Convey("metrics record a timeout, not a failure", func() {
+					time.Sleep(10 * time.Millisecond)
+					cb, _, _ := GetCircuit("")
+					So(cb.metrics.DefaultCollector().Timeouts().Sum(time.Now()), ShouldEqual, 1)
+					So(cb.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 0)
+				})
+			})
+		})
+	})
+}
+
+func TestGoCCallerCancel(t *testing.T) {
+	Convey("with a context-aware command whose caller gives up", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{Timeout: 10000})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		fallbackErr := make(chan error, 1)
+		errChan := GoC(ctx, "", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, func(ctx context.Context, err error) error {
+			fallbackErr <- err
+			return err
+		})
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+
+		Convey("the fallback receives the context error", func() {
+			So(<-fallbackErr, ShouldEqual, context.Canceled)
+			So((<-errChan).Error(), ShouldContainSubstring, "context canceled")
+
+			Convey("metrics record a cancellation, not a failure", func() {
+				time.Sleep(10 * time.Millisecond)
+				cb, _, _ := GetCircuit("")
+				So(cb.metrics.DefaultCollector().ContextCanceled().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 0)
+				So(cb.metrics.DefaultCollector().Errors().Sum(time.Now()), ShouldEqual, 0)
+			})
+		})
+	})
+
+	Convey("with a command ignoring its context whose caller gives up", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{Timeout: 10000})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errChan := GoC(ctx, "", func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}, nil)
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		So((<-errChan).Error(), ShouldContainSubstring, "context canceled")
+
+		Convey("metrics record the cancellation, not a success", func() {
+			cb, _, _ := GetCircuit("")
+			for cb.metrics.DefaultCollector().ContextCanceled().Sum(time.Now()) < 1 {
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(10 * time.Millisecond)
+			So(cb.metrics.DefaultCollector().Successes().Sum(time.Now()), ShouldEqual, 0)
+		})
+	})
+}
+
+func TestDoC(t *testing.T) {
+	Convey("with a context-aware command which succeeds", t, func() {
+		defer Flush()
+
+		err := DoC(context.Background(), "", func(ctx context.Context) error {
+			return nil
+		}, nil)
+
+		Convey("no error is returned", func() {
+			So(err, ShouldBeNil)
+		})
+	})
+
+	Convey("with a context-aware command whose deadline passes", t, func() {
+		defer Flush()
+		ConfigureCommand("", CommandConfig{Timeout: 10000})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := DoC(ctx, "", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, nil)
+
+		Convey("the deadline error is returned", func() {
+			So(err, ShouldEqual, context.DeadlineExceeded)
+		})
+	})
+}
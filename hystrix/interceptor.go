@@ -0,0 +1,171 @@
+package hystrix
+
+import (
+	"context"
+	"time"
+)
+
+// Execution identifies a single execution of a command to interceptors.
+// The same *Execution is passed to every hook called for that execution.
+type Execution struct {
+	Name string
+	// Key is the key of commands started with DoKeyed, it is empty for others
+	Key          string
+	CommandGroup string
+	Start        time.Time
+}
+
+// An Interceptor is called at each phase of every command execution, e.g. to trace, log or audit them.
+//
+// Hooks returning a context replace the context of the following phases, which allows them to attach
+// values such as a tracing span. Hooks of several interceptors are called in the order the interceptors
+// were added for Before hooks and in reverse order for After hooks and Done, so that each interceptor
+// wraps those added after it. Interceptors added for all commands wrap those added for a single command.
+//
+// Embed NopInterceptor to implement only some of the hooks.
+//
+// Hooks are called from the goroutines running the command and must not block.
+type Interceptor interface {
+	// BeforeAcquire is called before the command asks its circuit for an execution slot.
+	BeforeAcquire(ctx context.Context, exec *Execution) context.Context
+	// AfterAcquire is called once the command got an execution slot, after waiting in queue for wait,
+	// or with the error which rejected it.
+	AfterAcquire(ctx context.Context, exec *Execution, wait time.Duration, err error)
+	// BeforeRun is called before the run function, with the context passed to it.
+	BeforeRun(ctx context.Context, exec *Execution) context.Context
+	// AfterRun is called when the run function returns, which may happen after the command timed out.
+	AfterRun(ctx context.Context, exec *Execution, duration time.Duration, err error)
+	// BeforeFallback is called before the fallback function with the error which triggered it.
+	BeforeFallback(ctx context.Context, exec *Execution, err error) context.Context
+	// AfterFallback is called when the fallback function returns.
+	AfterFallback(ctx context.Context, exec *Execution, err error)
+	// Done is called once the result of the command is known.
+	Done(ctx context.Context, exec *Execution, result *ExecutionResult)
+}
+
+// NopInterceptor implements every hook of Interceptor without doing anything.
+type NopInterceptor struct{}
+
+// BeforeAcquire returns ctx.
+func (NopInterceptor) BeforeAcquire(ctx context.Context, exec *Execution) context.Context {
+	return ctx
+}
+
+// AfterAcquire does nothing.
+func (NopInterceptor) AfterAcquire(ctx context.Context, exec *Execution, wait time.Duration, err error) {
+}
+
+// BeforeRun returns ctx.
+func (NopInterceptor) BeforeRun(ctx context.Context, exec *Execution) context.Context {
+	return ctx
+}
+
+// AfterRun does nothing.
+func (NopInterceptor) AfterRun(ctx context.Context, exec *Execution, duration time.Duration, err error) {
+}
+
+// BeforeFallback returns ctx.
+func (NopInterceptor) BeforeFallback(ctx context.Context, exec *Execution, err error) context.Context {
+	return ctx
+}
+
+// AfterFallback does nothing.
+func (NopInterceptor) AfterFallback(ctx context.Context, exec *Execution, err error) {}
+
+// Done does nothing.
+func (NopInterceptor) Done(ctx context.Context, exec *Execution, result *ExecutionResult) {}
+
+// AddInterceptor adds an interceptor called for the commands of all circuits.
+func AddInterceptor(interceptor Interceptor) {
+	defaultRegistry.AddInterceptor(interceptor)
+}
+
+// AddInterceptor adds an interceptor called for the commands of all circuits of this registry.
+// It applies to commands started afterwards.
+func (r *Registry) AddInterceptor(interceptor Interceptor) {
+	r.interceptorsMutex.Lock()
+	defer r.interceptorsMutex.Unlock()
+
+	r.interceptors = r.interceptors.with(interceptor)
+}
+
+// AddCommandInterceptor adds an interceptor called for the given command only.
+func AddCommandInterceptor(name string, interceptor Interceptor) {
+	defaultRegistry.AddCommandInterceptor(name, interceptor)
+}
+
+// AddCommandInterceptor adds an interceptor called for the given command of this registry only.
+// It applies to commands started afterwards.
+func (r *Registry) AddCommandInterceptor(name string, interceptor Interceptor) {
+	r.interceptorsMutex.Lock()
+	defer r.interceptorsMutex.Unlock()
+
+	r.commandInterceptors[name] = r.commandInterceptors[name].with(interceptor)
+}
+
+// getInterceptors returns the interceptors of a command, those of all commands first.
+func (r *Registry) getInterceptors(name string) interceptors {
+	r.interceptorsMutex.RLock()
+	defer r.interceptorsMutex.RUnlock()
+
+	command := r.commandInterceptors[name]
+	if len(command) == 0 {
+		return r.interceptors
+	}
+	return r.interceptors.with(command...)
+}
+
+// interceptors calls the hooks of each interceptor in turn. The slice is never modified
+// once in use by a command, adding an interceptor replaces it.
+type interceptors []Interceptor
+
+// with returns a new slice holding is followed by more.
+func (is interceptors) with(more ...Interceptor) interceptors {
+	merged := make(interceptors, 0, len(is)+len(more))
+	return append(append(merged, is...), more...)
+}
+
+func (is interceptors) beforeAcquire(ctx context.Context, exec *Execution) context.Context {
+	for _, i := range is {
+		ctx = i.BeforeAcquire(ctx, exec)
+	}
+	return ctx
+}
+
+func (is interceptors) afterAcquire(ctx context.Context, exec *Execution, wait time.Duration, err error) {
+	for n := len(is) - 1; n >= 0; n-- {
+		is[n].AfterAcquire(ctx, exec, wait, err)
+	}
+}
+
+func (is interceptors) beforeRun(ctx context.Context, exec *Execution) context.Context {
+	for _, i := range is {
+		ctx = i.BeforeRun(ctx, exec)
+	}
+	return ctx
+}
+
+func (is interceptors) afterRun(ctx context.Context, exec *Execution, duration time.Duration, err error) {
+	for n := len(is) - 1; n >= 0; n-- {
+		is[n].AfterRun(ctx, exec, duration, err)
+	}
+}
+
+func (is interceptors) beforeFallback(ctx context.Context, exec *Execution, err error) context.Context {
+	for _, i := range is {
+		ctx = i.BeforeFallback(ctx, exec, err)
+	}
+	return ctx
+}
+
+func (is interceptors) afterFallback(ctx context.Context, exec *Execution, err error) {
+	for n := len(is) - 1; n >= 0; n-- {
+		is[n].AfterFallback(ctx, exec, err)
+	}
+}
+
+func (is interceptors) done(ctx context.Context, exec *Execution, result *ExecutionResult) {
+	for n := len(is) - 1; n >= 0; n-- {
+		is[n].Done(ctx, exec, result)
+	}
+}
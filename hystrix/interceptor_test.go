@@ -0,0 +1,202 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type ctxKey string
+
+// callLog is shared by the recordingInterceptors of a test to check the order of their hooks.
+type callLog struct {
+	mu    sync.Mutex
+	calls []string
+	done  chan []EventType
+}
+
+func (l *callLog) record(call string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls = append(l.calls, call)
+}
+
+func (l *callLog) Calls() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.calls...)
+}
+
+// recordingInterceptor records the hooks called on it, prefixed by its name.
+type recordingInterceptor struct {
+	name string
+	log  *callLog
+}
+
+func (i *recordingInterceptor) BeforeAcquire(ctx context.Context, exec *Execution) context.Context {
+	i.log.record(i.name + ":BeforeAcquire")
+	return context.WithValue(ctx, ctxKey(i.name), exec.Name)
+}
+
+func (i *recordingInterceptor) AfterAcquire(ctx context.Context, exec *Execution, wait time.Duration, err error) {
+	i.log.record(fmt.Sprintf("%s:AfterAcquire(%v)", i.name, err))
+}
+
+func (i *recordingInterceptor) BeforeRun(ctx context.Context, exec *Execution) context.Context {
+	i.log.record(i.name + ":BeforeRun")
+	return ctx
+}
+
+func (i *recordingInterceptor) AfterRun(ctx context.Context, exec *Execution, duration time.Duration, err error) {
+	i.log.record(fmt.Sprintf("%s:AfterRun(%v)", i.name, err))
+}
+
+func (i *recordingInterceptor) BeforeFallback(ctx context.Context, exec *Execution, err error) context.Context {
+	i.log.record(fmt.Sprintf("%s:BeforeFallback(%v)", i.name, err))
+	return ctx
+}
+
+func (i *recordingInterceptor) AfterFallback(ctx context.Context, exec *Execution, err error) {
+	i.log.record(fmt.Sprintf("%s:AfterFallback(%v)", i.name, err))
+}
+
+func (i *recordingInterceptor) Done(ctx context.Context, exec *Execution, result *ExecutionResult) {
+	i.log.record(fmt.Sprintf("%s:Done(%v)", i.name, result.Err))
+	if i.log.done != nil {
+		i.log.done <- result.Events
+	}
+}
+
+func TestInterceptor(t *testing.T) {
+	Convey("given a registry with an interceptor", t, func() {
+		registry := NewRegistry()
+		log := &callLog{done: make(chan []EventType, 10)}
+		registry.AddInterceptor(&recordingInterceptor{name: "first", log: log})
+
+		Convey("a successful command calls the hooks of each phase", func() {
+			var value interface{}
+			err := registry.DoC(context.Background(), "intercepted", func(ctx context.Context) error {
+				value = ctx.Value(ctxKey("first"))
+				return nil
+			}, nil)
+			So(err, ShouldBeNil)
+
+			So(<-log.done, ShouldResemble, []EventType{EventSuccess})
+			So(log.Calls(), ShouldResemble, []string{"first:BeforeAcquire", "first:AfterAcquire(<nil>)", "first:BeforeRun", "first:AfterRun(<nil>)", "first:Done(<nil>)"})
+
+			Convey("and the context returned by BeforeAcquire reaches run", func() {
+				So(value, ShouldEqual, "intercepted")
+			})
+		})
+
+		Convey("a failing command calls the fallback hooks", func() {
+			err := registry.Do("intercepted", func() error {
+				return fmt.Errorf("run failed")
+			}, func(err error) error {
+				return fmt.Errorf("fallback failed")
+			})
+			So(err, ShouldNotBeNil)
+
+			So(<-log.done, ShouldResemble, []EventType{EventFailure, EventFallbackFailure})
+			So(log.Calls(), ShouldResemble, []string{
+				"first:BeforeAcquire", "first:AfterAcquire(<nil>)", "first:BeforeRun", "first:AfterRun(run failed)",
+				"first:BeforeFallback(run failed)", "first:AfterFallback(fallback failed)", "first:Done(" + err.Error() + ")",
+			})
+		})
+
+		Convey("a rejected command reports the error from AfterAcquire", func() {
+			registry.ConfigureCommand("intercepted", CommandConfig{MaxConcurrentRequests: 1, QueueSizeRejectionThreshold: 1, Timeout: 10000})
+			block := make(chan struct{})
+			blocked := func() error {
+				<-block
+				return nil
+			}
+			registry.Go("intercepted", blocked, nil)
+			registry.Go("intercepted", blocked, nil)
+
+			cb, _, _ := registry.GetCircuit("intercepted")
+			for cb.ActiveCount() < 1 || cb.WaitingCount() < 1 {
+				time.Sleep(time.Millisecond)
+			}
+
+			err := registry.Do("intercepted", func() error { return nil }, nil)
+			So(err, ShouldResemble, ErrMaxConcurrency)
+			So(<-log.done, ShouldResemble, []EventType{EventRejected})
+			So(log.Calls(), ShouldContain, "first:AfterAcquire(hystrix: max concurrency)")
+
+			close(block)
+			<-log.done
+			<-log.done
+		})
+
+		Convey("with a second interceptor", func() {
+			registry.AddInterceptor(&recordingInterceptor{name: "second", log: log})
+
+			Convey("its hooks are nested within those of the first", func() {
+				err := registry.Do("intercepted", func() error { return nil }, nil)
+				So(err, ShouldBeNil)
+				<-log.done
+				<-log.done
+
+				So(log.Calls(), ShouldResemble, []string{
+					"first:BeforeAcquire", "second:BeforeAcquire", "second:AfterAcquire(<nil>)", "first:AfterAcquire(<nil>)",
+					"first:BeforeRun", "second:BeforeRun", "second:AfterRun(<nil>)", "first:AfterRun(<nil>)",
+					"second:Done(<nil>)", "first:Done(<nil>)",
+				})
+			})
+		})
+	})
+}
+
+// auditInterceptor only counts the executions which are done.
+type auditInterceptor struct {
+	NopInterceptor
+	done chan string
+}
+
+func (i *auditInterceptor) Done(ctx context.Context, exec *Execution, result *ExecutionResult) {
+	i.done <- exec.Name
+}
+
+func TestCommandInterceptor(t *testing.T) {
+	Convey("given an interceptor added for a single command", t, func() {
+		registry := NewRegistry()
+		audit := &auditInterceptor{done: make(chan string, 10)}
+		registry.AddCommandInterceptor("audited", audit)
+
+		log := &callLog{}
+		registry.AddInterceptor(&recordingInterceptor{name: "global", log: log})
+
+		Convey("it is called for that command", func() {
+			So(registry.Do("audited", func() error { return nil }, nil), ShouldBeNil)
+			So(<-audit.done, ShouldEqual, "audited")
+		})
+
+		Convey("it is not called for other commands", func() {
+			So(registry.Do("other", func() error { return nil }, nil), ShouldBeNil)
+			select {
+			case name := <-audit.done:
+				t.Fatalf("interceptor called for %v", name)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+
+		Convey("interceptors of all commands wrap it", func() {
+			registry.AddCommandInterceptor("audited", &recordingInterceptor{name: "command", log: log})
+			So(registry.Do("audited", func() error { return nil }, nil), ShouldBeNil)
+			<-audit.done
+
+			for len(log.Calls()) < 10 {
+				time.Sleep(time.Millisecond)
+			}
+			calls := log.Calls()
+			So(calls[0], ShouldEqual, "global:BeforeAcquire")
+			So(calls[1], ShouldEqual, "command:BeforeAcquire")
+			So(calls[len(calls)-1], ShouldEqual, "global:Done(<nil>)")
+		})
+	})
+}
@@ -0,0 +1,198 @@
+package hystrix
+
+import (
+	"context"
+	"time"
+)
+
+// IsolationStrategy selects how the commands of a circuit are executed.
+type IsolationStrategy int
+
+const (
+	// IsolationThread runs every command on a goroutine of its own and waits for it on another,
+	// so that commands time out even when their run function ignores its context.
+	IsolationThread IsolationStrategy = iota
+	// IsolationSemaphore runs the commands of Do and DoC on the calling goroutine, and those of Go, GoC and
+	// Execute on a single goroutine, limited by MaxConcurrentRequests without a queue. Timeouts are only
+	// enforced through the context passed to run, so it suits cheap calls such as in-memory lookups,
+	// where the goroutines of IsolationThread dominate the cost.
+	IsolationSemaphore
+	// IsolationWorkerPool runs commands on MaxConcurrentRequests long-lived goroutines per circuit, queueing up to
+	// QueueSizeRejectionThreshold commands in order. The commands of Do and DoC are waited for on the calling
+	// goroutine, so the number of goroutines stays the same however overloaded the circuit is.
+	IsolationWorkerPool
+)
+
+var isolationStrategyNames = map[IsolationStrategy]string{
+	IsolationThread:     "thread",
+	IsolationSemaphore:  "semaphore",
+	IsolationWorkerPool: "worker-pool",
+}
+
+// String returns the name of the strategy, e.g. "semaphore".
+func (s IsolationStrategy) String() string {
+	if name, ok := isolationStrategyNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseIsolationStrategy returns the IsolationStrategy with the given name.
+func ParseIsolationStrategy(name string) (IsolationStrategy, bool) {
+	for s, n := range isolationStrategyNames {
+		if n == name {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// execute runs the command with a strategy other than IsolationThread on the calling goroutine.
+func (c *command) execute(ctx context.Context, strategy IsolationStrategy) {
+	if strategy == IsolationWorkerPool {
+		c.runOnWorkers(ctx)
+		return
+	}
+	c.runInline(ctx)
+}
+
+// runInline executes the command on the calling goroutine for circuits using IsolationSemaphore.
+// The execution slot is taken without waiting, and errors are sent to errChan like for commands started by GoC.
+func (c *command) runInline(ctx context.Context) {
+	ctx = c.interceptors.beforeAcquire(ctx, c.execution)
+	defer c.report(ctx)
+
+	allowed, trialRound := c.circuit.allowRequest()
+	c.trialRound = trialRound
+	if !allowed {
+		c.interceptors.afterAcquire(ctx, c.execution, 0, ErrCircuitOpen)
+		c.errorWithFallback(ctx, ErrCircuitOpen)
+		return
+	}
+
+	tickets, _ := c.circuit.executorPool.tickets()
+	select {
+	case t := <-tickets:
+		c.setTicket(t)
+	default:
+		c.interceptors.afterAcquire(ctx, c.execution, 0, ErrMaxConcurrency)
+		c.errorWithFallback(ctx, ErrMaxConcurrency)
+		return
+	}
+	c.interceptors.afterAcquire(ctx, c.execution, 0, nil)
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.circuit.settings().Timeout)
+	defer cancel()
+
+	runCtx := c.interceptors.beforeRun(timeoutCtx, c.execution)
+	runStart := time.Now()
+	runErr := c.run(runCtx)
+	c.interceptors.afterRun(runCtx, c.execution, time.Since(runStart), runErr)
+
+	if ctx.Err() == nil && timeoutCtx.Err() != nil {
+		// the run function could not be abandoned, so it is reported as a timeout once it returns
+		c.errorWithFallback(ctx, ErrTimeout)
+		return
+	}
+
+	c.setRunDuration(time.Since(runStart))
+
+	// like commands started by GoC, those whose caller gave up are not successes even when run ignored ctx
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		runErr = ctxErr
+	}
+	if runErr != nil {
+		c.errorWithFallback(ctx, runErr)
+		return
+	}
+
+	c.reportEvent(EventSuccess)
+}
+
+// runOnWorkers submits the command to the worker pool of its circuit for circuits using IsolationWorkerPool,
+// and waits on the calling goroutine for its result, its timeout or ctx. Errors are sent to errChan like for
+// commands started by GoC.
+func (c *command) runOnWorkers(ctx context.Context) {
+	ctx = c.interceptors.beforeAcquire(ctx, c.execution)
+	defer c.report(ctx)
+
+	allowed, trialRound := c.circuit.allowRequest()
+	c.trialRound = trialRound
+	if !allowed {
+		c.interceptors.afterAcquire(ctx, c.execution, 0, ErrCircuitOpen)
+		c.errorWithFallback(ctx, ErrCircuitOpen)
+		return
+	}
+
+	timer := time.NewTimer(c.circuit.settings().Timeout)
+	defer timer.Stop()
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	type runResult struct {
+		err      error
+		duration time.Duration
+	}
+	results := make(chan runResult, 1)
+	submitted := time.Now()
+
+	run := func() {
+		wait := time.Since(submitted)
+		c.setQueueWait(wait)
+		c.interceptors.afterAcquire(ctx, c.execution, wait, nil)
+
+		runCtx := c.interceptors.beforeRun(runCtx, c.execution)
+		runStart := time.Now()
+		runErr := c.run(runCtx)
+		c.interceptors.afterRun(runCtx, c.execution, time.Since(runStart), runErr)
+		results <- runResult{err: runErr, duration: time.Since(runStart)}
+	}
+
+	workers := c.circuit.workerPool()
+	task, queued := workers.Submit(run)
+	if task == nil {
+		c.interceptors.afterAcquire(ctx, c.execution, 0, ErrMaxConcurrency)
+		c.errorWithFallback(ctx, ErrMaxConcurrency)
+		return
+	}
+	if queued {
+		c.reportEvent(EventQueued)
+	}
+
+	// abandon gives up on a task which has not started yet, removing it from the queue, and returns whether it had
+	abandon := func(err error) bool {
+		if !workers.Cancel(task) {
+			return false
+		}
+		wait := time.Since(submitted)
+		c.setQueueWait(wait)
+		c.interceptors.afterAcquire(ctx, c.execution, wait, err)
+		return true
+	}
+
+	select {
+	case result := <-results:
+		c.setRunDuration(result.duration)
+		// the result may arrive together with the end of ctx, which takes precedence like for inline commands
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			result.err = ctxErr
+		}
+		if result.err != nil {
+			c.errorWithFallback(ctx, result.err)
+			return
+		}
+		c.reportEvent(EventSuccess)
+	case <-ctx.Done():
+		cancelRun()
+		abandon(ctx.Err())
+		c.errorWithFallback(ctx, ctx.Err())
+	case <-timer.C:
+		cancelRun()
+		// like commands waiting for an execution ticket, those which never left the queue are rejected
+		if abandon(ErrMaxConcurrency) {
+			c.errorWithFallback(ctx, ErrMaxConcurrency)
+			return
+		}
+		c.errorWithFallback(ctx, ErrTimeout)
+	}
+}
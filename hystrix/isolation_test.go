@@ -0,0 +1,200 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSemaphoreIsolation(t *testing.T) {
+	Convey("given a circuit using semaphore isolation", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("inline", CommandConfig{
+			IsolationStrategy:     "semaphore",
+			MaxConcurrentRequests: 1,
+			Timeout:               20,
+		})
+		cb, _, _ := registry.GetCircuit("inline")
+		log := &callLog{done: make(chan []EventType, 10)}
+		registry.AddInterceptor(&recordingInterceptor{name: "audit", log: log})
+
+		Convey("Do runs the command on the calling goroutine", func() {
+			recovered := func() (r interface{}) {
+				defer func() { r = recover() }()
+				_ = registry.Do("inline", func() error { panic("inline") }, nil)
+				return nil
+			}()
+			So(recovered, ShouldEqual, "inline")
+		})
+
+		Convey("a successful command is reported", func() {
+			So(registry.Do("inline", func() error { return nil }, nil), ShouldBeNil)
+			So(<-log.done, ShouldResemble, []EventType{EventSuccess})
+			So(cb.ActiveCount(), ShouldEqual, 0)
+		})
+
+		Convey("a command which overruns its timeout falls back", func() {
+			err := registry.DoC(context.Background(), "inline", func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}, func(ctx context.Context, err error) error {
+				return fmt.Errorf("fallback after %v", err)
+			})
+			So(err.Error(), ShouldContainSubstring, "fallback after hystrix: timeout")
+			So(<-log.done, ShouldResemble, []EventType{EventTimeout, EventFallbackFailure})
+		})
+
+		Convey("a command ignoring its context whose caller gives up is not a success", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(5*time.Millisecond, cancel)
+			err := registry.DoC(ctx, "inline", func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			}, nil)
+			So(err, ShouldEqual, context.Canceled)
+			So(<-log.done, ShouldResemble, []EventType{EventContextCanceled})
+		})
+
+		Convey("a command is rejected without queueing when the semaphore is taken", func() {
+			block := make(chan struct{})
+			registry.GoC(context.Background(), "inline", func(ctx context.Context) error {
+				<-block
+				return nil
+			}, nil)
+			for cb.ActiveCount() < 1 {
+				time.Sleep(time.Millisecond)
+			}
+
+			So(registry.Do("inline", func() error { return nil }, nil), ShouldResemble, ErrMaxConcurrency)
+			So(<-log.done, ShouldResemble, []EventType{EventRejected})
+
+			close(block)
+			So(<-log.done, ShouldResemble, []EventType{EventSuccess})
+		})
+
+		Convey("an open circuit short-circuits", func() {
+			cb.ForceOpen()
+			So(registry.Do("inline", func() error { return nil }, nil), ShouldResemble, ErrCircuitOpen)
+			So(<-log.done, ShouldResemble, []EventType{EventShortCircuit})
+		})
+
+		Convey("typed results work the same", func() {
+			v, err := ExecuteOn(registry, context.Background(), "inline", func(ctx context.Context) (int, error) {
+				return 42, nil
+			}, nil)
+			So(err, ShouldBeNil)
+			So(v, ShouldEqual, 42)
+		})
+	})
+}
+
+func TestWorkerPoolIsolation(t *testing.T) {
+	Convey("given a circuit using worker pool isolation with one worker and a queue of one", t, func() {
+		registry := NewRegistry()
+		defer registry.Flush()
+		registry.ConfigureCommand("workers", CommandConfig{
+			IsolationStrategy:           "worker-pool",
+			MaxConcurrentRequests:       1,
+			QueueSizeRejectionThreshold: 1,
+			Timeout:                     50,
+		})
+		cb, _, _ := registry.GetCircuit("workers")
+		log := &callLog{done: make(chan []EventType, 10)}
+		registry.AddInterceptor(&recordingInterceptor{name: "audit", log: log})
+
+		Convey("a successful command is reported", func() {
+			So(registry.Do("workers", func() error { return nil }, nil), ShouldBeNil)
+			So(<-log.done, ShouldResemble, []EventType{EventSuccess})
+		})
+
+		Convey("a command which overruns its timeout falls back", func() {
+			err := registry.Do("workers", func() error {
+				time.Sleep(100 * time.Millisecond)
+				return nil
+			}, func(err error) error {
+				return fmt.Errorf("fallback after %v", err)
+			})
+			So(err.Error(), ShouldContainSubstring, "fallback after hystrix: timeout")
+			So(<-log.done, ShouldResemble, []EventType{EventTimeout, EventFallbackFailure})
+		})
+
+		Convey("a command whose result arrives after its caller gave up is not a success", func() {
+			ctx := &endingContext{Context: context.Background()}
+			err := registry.DoC(ctx, "workers", func(context.Context) error {
+				ctx.end(context.Canceled)
+				return nil
+			}, nil)
+			So(err, ShouldEqual, context.Canceled)
+			So(<-log.done, ShouldResemble, []EventType{EventContextCanceled})
+		})
+
+		Convey("commands queue behind a busy worker and are rejected beyond the queue", func() {
+			block := make(chan struct{})
+			registry.GoC(context.Background(), "workers", func(ctx context.Context) error {
+				<-block
+				return nil
+			}, nil)
+			for cb.ActiveCount() < 1 {
+				time.Sleep(time.Millisecond)
+			}
+			registry.GoC(context.Background(), "workers", func(ctx context.Context) error {
+				return nil
+			}, nil)
+			for cb.WaitingCount() < 1 {
+				time.Sleep(time.Millisecond)
+			}
+
+			So(registry.Do("workers", func() error { return nil }, nil), ShouldResemble, ErrMaxConcurrency)
+			So(<-log.done, ShouldResemble, []EventType{EventRejected})
+
+			close(block)
+			results := [][]EventType{<-log.done, <-log.done}
+			So(results, ShouldContain, []EventType{EventSuccess})
+			So(results, ShouldContain, []EventType{EventQueued, EventSuccess})
+		})
+
+		Convey("a queued command which never starts before its timeout is rejected", func() {
+			block := make(chan struct{})
+			defer close(block)
+			registry.GoC(context.Background(), "workers", func(ctx context.Context) error {
+				<-block
+				return nil
+			}, nil)
+			for cb.ActiveCount() < 1 {
+				time.Sleep(time.Millisecond)
+			}
+
+			ran := false
+			So(registry.Do("workers", func() error { ran = true; return nil }, nil), ShouldResemble, ErrMaxConcurrency)
+			// and leaves the queue
+			So(cb.WaitingCount(), ShouldEqual, 0)
+			// the busy command times out at about the same time
+			results := [][]EventType{<-log.done, <-log.done}
+			So(results, ShouldContain, []EventType{EventQueued, EventRejected})
+			So(results, ShouldContain, []EventType{EventTimeout})
+			So(ran, ShouldBeFalse)
+		})
+	})
+}
+
+// endingContext ends without closing its Done channel, so that a result which arrives together with its end
+// is always received first.
+type endingContext struct {
+	context.Context
+	err atomic.Value
+}
+
+func (c *endingContext) end(err error) {
+	c.err.Store(err)
+}
+
+func (c *endingContext) Err() error {
+	if err, ok := c.err.Load().(error); ok {
+		return err
+	}
+	return nil
+}
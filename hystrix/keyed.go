@@ -0,0 +1,212 @@
+package hystrix
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// keyedCircuits holds the circuits created by DoKeyed for the keys of one command.
+type keyedCircuits struct {
+	circuits map[string]*keyedCircuit
+	// sweptAt is when circuits idle for longer than KeyIdleTimeout were last evicted
+	sweptAt time.Time
+}
+
+type keyedCircuit struct {
+	circuit *CircuitBreaker
+	// lastUsed is the time in nanoseconds the last command of the key started, updated atomically
+	lastUsed int64
+}
+
+// DoKeyed runs your function like Do, on a circuit of its own for the given key of the command, e.g. the host or
+// the tenant it calls, so that one failing host does not open the circuit of the others. See Registry.DoKeyed.
+func DoKeyed(name string, key string, run runFunc, fallback fallbackFunc) error {
+	return defaultRegistry.DoKeyed(name, key, run, fallback)
+}
+
+// DoKeyed runs your function like Do, on a circuit of this registry for the given key of the command.
+//
+// The circuit of a key is created by its first command, with the settings of the command and an executor pool
+// of its own, sized by them. Its state and metrics are independent of those of the command and its other keys,
+// and it is named after both, e.g. "payments:host-a". At most MaxKeys circuits are kept for a command, the least
+// recently used one is evicted to make room for a new key, and those without any command for KeyIdleTimeout are
+// evicted by the next keyed command of the command once every KeyIdleTimeout. An empty key runs the command on
+// the circuit of the command itself.
+func (r *Registry) DoKeyed(name string, key string, run runFunc, fallback fallbackFunc) error {
+	runC := func(ctx context.Context) error {
+		return run()
+	}
+	var fallbackC fallbackFuncC
+	if fallback != nil {
+		fallbackC = func(ctx context.Context, err error) error {
+			return fallback(err)
+		}
+	}
+	return r.DoKeyedC(context.Background(), name, key, runC, fallbackC)
+}
+
+// DoKeyedC runs your function like DoC, on a circuit of its own for the given key of the command.
+func DoKeyedC(ctx context.Context, name string, key string, run runFuncC, fallback fallbackFuncC) error {
+	return defaultRegistry.DoKeyedC(ctx, name, key, run, fallback)
+}
+
+// DoKeyedC runs your function like DoC, on a circuit of this registry for the given key of the command, see DoKeyed.
+func (r *Registry) DoKeyedC(ctx context.Context, name string, key string, run runFuncC, fallback fallbackFuncC) error {
+	if key == "" {
+		return r.DoC(ctx, name, run, fallback)
+	}
+	return r.doC(ctx, r.getKeyedCircuit(name, key), run, fallback)
+}
+
+// KeyedCircuits returns the circuits of the given command created by DoKeyed which were not evicted, keyed by key.
+func KeyedCircuits(name string) map[string]*CircuitBreaker {
+	return defaultRegistry.KeyedCircuits(name)
+}
+
+// KeyedCircuits returns the circuits of the given command of this registry created by DoKeyed which were not
+// evicted, keyed by key.
+func (r *Registry) KeyedCircuits(name string) map[string]*CircuitBreaker {
+	circuits := make(map[string]*CircuitBreaker)
+	for _, cb := range r.getKeyedCircuits(name) {
+		circuits[cb.Key] = cb
+	}
+	return circuits
+}
+
+// keyedCircuitName returns the name of the circuit of the given key of a command.
+func keyedCircuitName(name string, key string) string {
+	return name + ":" + key
+}
+
+// getKeyedCircuit returns the circuit for the given key of a command, creating it on the first command of the key.
+func (r *Registry) getKeyedCircuit(name string, key string) *CircuitBreaker {
+	settings := r.getSettings(name)
+	idleTimeout := settings.keyIdleTimeout()
+	now := time.Now()
+
+	r.keyedCircuitsMutex.RLock()
+	keyed := r.keyedCircuits[name]
+	if keyed != nil && now.Sub(keyed.sweptAt) < idleTimeout {
+		if kc, ok := keyed.circuits[key]; ok {
+			atomic.StoreInt64(&kc.lastUsed, now.UnixNano())
+			r.keyedCircuitsMutex.RUnlock()
+			return kc.circuit
+		}
+	}
+	r.keyedCircuitsMutex.RUnlock()
+
+	r.keyedCircuitsMutex.Lock()
+	defer r.keyedCircuitsMutex.Unlock()
+
+	keyed = r.keyedCircuits[name]
+	if keyed == nil {
+		keyed = &keyedCircuits{
+			circuits: make(map[string]*keyedCircuit),
+			sweptAt:  now,
+		}
+		r.keyedCircuits[name] = keyed
+	}
+	if now.Sub(keyed.sweptAt) >= idleTimeout {
+		keyed.evictIdle(now.Add(-idleTimeout))
+		keyed.sweptAt = now
+	}
+
+	kc, ok := keyed.circuits[key]
+	if !ok {
+		// MaxKeys may have been lowered since the last key was added
+		for len(keyed.circuits) >= settings.maxKeys() {
+			keyed.evictLeastRecentlyUsed()
+		}
+		kc = &keyedCircuit{circuit: newCircuitBreaker(r, name, key)}
+		keyed.circuits[key] = kc
+	}
+	atomic.StoreInt64(&kc.lastUsed, now.UnixNano())
+	return kc.circuit
+}
+
+// getKeyedCircuits returns the circuits of the keys of the given command.
+func (r *Registry) getKeyedCircuits(name string) []*CircuitBreaker {
+	r.keyedCircuitsMutex.RLock()
+	defer r.keyedCircuitsMutex.RUnlock()
+
+	keyed := r.keyedCircuits[name]
+	if keyed == nil {
+		return nil
+	}
+	circuits := make([]*CircuitBreaker, 0, len(keyed.circuits))
+	for _, kc := range keyed.circuits {
+		circuits = append(circuits, kc.circuit)
+	}
+	return circuits
+}
+
+// allCircuits returns the circuits of the registry together with the keyed circuits of its commands.
+func (r *Registry) allCircuits() []*CircuitBreaker {
+	r.circuitBreakersMutex.RLock()
+	circuits := make([]*CircuitBreaker, 0, len(r.circuitBreakers))
+	for _, cb := range r.circuitBreakers {
+		circuits = append(circuits, cb)
+	}
+	r.circuitBreakersMutex.RUnlock()
+
+	r.keyedCircuitsMutex.RLock()
+	defer r.keyedCircuitsMutex.RUnlock()
+
+	for _, keyed := range r.keyedCircuits {
+		for _, kc := range keyed.circuits {
+			circuits = append(circuits, kc.circuit)
+		}
+	}
+	return circuits
+}
+
+// flushKeyedCircuits stops the keyed circuits of the registry and forgets them.
+func (r *Registry) flushKeyedCircuits() {
+	r.keyedCircuitsMutex.Lock()
+	defer r.keyedCircuitsMutex.Unlock()
+
+	for name, keyed := range r.keyedCircuits {
+		for key := range keyed.circuits {
+			keyed.evict(key)
+		}
+		delete(r.keyedCircuits, name)
+	}
+}
+
+// evictIdle evicts the circuits whose last command started before idleSince, the caller must hold the mutex
+// of the registry.
+func (k *keyedCircuits) evictIdle(idleSince time.Time) {
+	for key, kc := range k.circuits {
+		if atomic.LoadInt64(&kc.lastUsed) < idleSince.UnixNano() {
+			k.evict(key)
+		}
+	}
+}
+
+// evictLeastRecentlyUsed evicts the circuit whose last command started first, the caller must hold the mutex
+// of the registry.
+func (k *keyedCircuits) evictLeastRecentlyUsed() {
+	oldestKey := ""
+	oldest := int64(0)
+	for key, kc := range k.circuits {
+		if lastUsed := atomic.LoadInt64(&kc.lastUsed); oldestKey == "" || lastUsed < oldest {
+			oldestKey = key
+			oldest = lastUsed
+		}
+	}
+	k.evict(oldestKey)
+}
+
+// evict stops the circuit of the given key and forgets it. Commands still running on it complete, but their
+// results are no longer collected.
+func (k *keyedCircuits) evict(key string) {
+	k.circuits[key].circuit.stop()
+	delete(k.circuits, key)
+}
+
+// stop ends the goroutines of the metrics and of the executor pool of a keyed circuit, which must no longer be used.
+func (circuit *CircuitBreaker) stop() {
+	circuit.metrics.Stop()
+	circuit.threadPool.stop()
+}
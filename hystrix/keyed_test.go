@@ -0,0 +1,86 @@
+package hystrix
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDoKeyed(t *testing.T) {
+	Convey("given a command run for several hosts", t, func() {
+		registry := NewRegistry()
+		defer registry.Flush()
+		registry.ConfigureCommand("payments", CommandConfig{RequestVolumeThreshold: 1, MaxKeys: 2, MaxConcurrentRequests: 3})
+
+		Convey("each host gets a circuit of its own named after both", func() {
+			So(registry.DoKeyed("payments", "host-a", func() error { return nil }, nil), ShouldBeNil)
+			So(registry.DoKeyed("payments", "host-b", func() error { return nil }, nil), ShouldBeNil)
+
+			circuits := registry.KeyedCircuits("payments")
+			So(len(circuits), ShouldEqual, 2)
+			So(circuits["host-a"].Name, ShouldEqual, "payments:host-a")
+			So(circuits["host-a"].Key, ShouldEqual, "host-a")
+			So(circuits["host-a"].executorPool == circuits["host-b"].executorPool, ShouldBeFalse)
+			max, _ := circuits["host-a"].executorPool.Size()
+			So(max, ShouldEqual, 3)
+
+			Convey("which are not circuits of the command itself", func() {
+				So(registry.Circuits(), ShouldBeEmpty)
+				So(registry.GetCircuitSettings(), ShouldContainKey, "payments")
+				So(registry.GetCircuitSettings(), ShouldNotContainKey, "payments:host-a")
+			})
+		})
+
+		Convey("a failing host only opens its own circuit", func() {
+			So(registry.DoKeyed("payments", "host-a", func() error { return errors.New("down") }, nil), ShouldNotBeNil)
+			So(registry.DoKeyed("payments", "host-b", func() error { return nil }, nil), ShouldBeNil)
+
+			circuits := registry.KeyedCircuits("payments")
+			for circuits["host-a"].Metrics().NumRequests().Sum(time.Now()) < 1 {
+				time.Sleep(time.Millisecond)
+			}
+			So(circuits["host-a"].IsOpen(), ShouldBeTrue)
+			So(circuits["host-b"].IsOpen(), ShouldBeFalse)
+			So(registry.DoKeyed("payments", "host-a", func() error { return nil }, nil), ShouldEqual, ErrCircuitOpen)
+		})
+
+		Convey("settings of the command apply to its keys", func() {
+			So(registry.DoKeyed("payments", "host-a", func() error { return nil }, nil), ShouldBeNil)
+			registry.ForceOpen("payments")
+
+			So(registry.KeyedCircuits("payments")["host-a"].IsOpen(), ShouldBeTrue)
+			So(registry.DoKeyed("payments", "host-b", func() error { return nil }, nil), ShouldEqual, ErrCircuitOpen)
+		})
+
+		Convey("a new key beyond MaxKeys evicts the least recently used", func() {
+			So(registry.DoKeyed("payments", "host-a", func() error { return nil }, nil), ShouldBeNil)
+			So(registry.DoKeyed("payments", "host-b", func() error { return nil }, nil), ShouldBeNil)
+			So(registry.DoKeyed("payments", "host-a", func() error { return nil }, nil), ShouldBeNil)
+			So(registry.DoKeyed("payments", "host-c", func() error { return nil }, nil), ShouldBeNil)
+
+			circuits := registry.KeyedCircuits("payments")
+			So(len(circuits), ShouldEqual, 2)
+			So(circuits, ShouldContainKey, "host-a")
+			So(circuits, ShouldContainKey, "host-c")
+		})
+
+		Convey("idle keys are evicted", func() {
+			registry.UpdateSettings("payments", func(s *Settings) { s.KeyIdleTimeout = 20 * time.Millisecond })
+			So(registry.DoKeyed("payments", "host-a", func() error { return nil }, nil), ShouldBeNil)
+			time.Sleep(30 * time.Millisecond)
+			So(registry.DoKeyed("payments", "host-b", func() error { return nil }, nil), ShouldBeNil)
+
+			circuits := registry.KeyedCircuits("payments")
+			So(len(circuits), ShouldEqual, 1)
+			So(circuits, ShouldContainKey, "host-b")
+		})
+
+		Convey("an empty key runs on the circuit of the command", func() {
+			So(registry.DoKeyed("payments", "", func() error { return nil }, nil), ShouldBeNil)
+			So(registry.Circuits(), ShouldContainKey, "payments")
+			So(registry.KeyedCircuits("payments"), ShouldBeEmpty)
+		})
+	})
+}
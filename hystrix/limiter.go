@@ -0,0 +1,216 @@
+package hystrix
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultLimitBackoffRatio is the factor by which LimitAIMD lowers the limit after a failure when an AdaptiveLimit sets none
+const DefaultLimitBackoffRatio = 0.9
+
+const (
+	// vegasProbeSamples is how many commands LimitVegas sees before it forgets the fastest run duration,
+	// so that a dependency which slowed down for good is not taken to be overloaded forever
+	vegasProbeSamples = 1000
+	// the number of commands the short and long averages of LimitGradient cover, and how much of the distance
+	// to its target the limit moves with each command
+	gradientShortWindow = 10
+	gradientLongWindow  = 600
+	gradientSmoothing   = 0.2
+)
+
+// LimitAlgorithm selects how an AdaptiveLimit adjusts the concurrency limit of an executor pool.
+type LimitAlgorithm int
+
+const (
+	// LimitAIMD raises the limit by one after each command which succeeded while the pool was at least half busy,
+	// and multiplies it by BackoffRatio after each command which failed or timed out. It only reacts to errors.
+	LimitAIMD LimitAlgorithm = iota
+	// LimitVegas estimates how many commands queue in the dependency from how much slower than the fastest recent
+	// command each command ran, and raises the limit while that queue holds fewer than 3 log10(limit) commands,
+	// lowering it once it holds more than 6 log10(limit) or commands fail.
+	LimitVegas
+	// LimitGradient lowers the limit in proportion to how much slower the last commands ran than the commands of
+	// the long run, down to half of it, and leaves room for a queue of the square root of the limit. Failures
+	// count like commands twice as slow as usual.
+	LimitGradient
+)
+
+var limitAlgorithmNames = map[LimitAlgorithm]string{
+	LimitAIMD:     "aimd",
+	LimitVegas:    "vegas",
+	LimitGradient: "gradient",
+}
+
+// String returns the name of the algorithm, e.g. "vegas".
+func (a LimitAlgorithm) String() string {
+	if name, ok := limitAlgorithmNames[a]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseLimitAlgorithm returns the LimitAlgorithm with the given name.
+func ParseLimitAlgorithm(name string) (LimitAlgorithm, bool) {
+	for a, n := range limitAlgorithmNames {
+		if n == name {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// AdaptiveLimit adjusts the number of commands the executor pool of a circuit runs at the same time from the run
+// durations and failures of its commands, between MinLimit and MaxConcurrentRequests. Commands beyond the limit
+// are queued or rejected exactly like with a static MaxConcurrentRequests. Only commands which ran are taken into
+// account, those which were rejected, short-circuited or abandoned by their caller are not.
+type AdaptiveLimit struct {
+	Algorithm LimitAlgorithm
+	// InitialLimit is the limit of a new executor pool, MaxConcurrentRequests when zero.
+	InitialLimit int
+	// MinLimit is the lowest the limit goes, 1 when zero.
+	MinLimit int
+	// BackoffRatio is the factor by which LimitAIMD lowers the limit after a failure, between 0 and 1.
+	BackoffRatio float64
+}
+
+// concurrencyLimiter holds the state of the AdaptiveLimit of a thread pool, which serializes calls to it.
+type concurrencyLimiter struct {
+	config AdaptiveLimit
+	min    int
+	max    int
+	limit  float64
+
+	// noLoadRTT is the fastest run duration seen by LimitVegas during the last vegasProbeSamples commands
+	noLoadRTT time.Duration
+	samples   int
+	// shortRTT and longRTT are the moving averages of the run durations of LimitGradient, in nanoseconds
+	shortRTT float64
+	longRTT  float64
+}
+
+func newConcurrencyLimiter(settings *Settings) *concurrencyLimiter {
+	l := &concurrencyLimiter{}
+	l.configure(settings)
+	l.limit = float64(settings.MaxConcurrentRequests)
+	if settings.AdaptiveLimit.InitialLimit > 0 {
+		l.limit = float64(settings.AdaptiveLimit.InitialLimit)
+	}
+	l.limit = l.bound(l.limit)
+	return l
+}
+
+// configure applies the AdaptiveLimit and MaxConcurrentRequests of settings, keeping the current limit within them.
+func (l *concurrencyLimiter) configure(settings *Settings) {
+	l.config = *settings.AdaptiveLimit
+	l.max = settings.MaxConcurrentRequests
+	l.min = 1
+	if l.config.MinLimit > 0 {
+		l.min = l.config.MinLimit
+	}
+	if l.min > l.max {
+		l.min = l.max
+	}
+	l.limit = l.bound(l.limit)
+}
+
+// Limit returns the current number of execution slots.
+func (l *concurrencyLimiter) Limit() int {
+	return int(l.limit)
+}
+
+// observe adjusts the limit for a command which ran for rtt while inflight commands, including itself, were running,
+// and which failed or timed out when dropped. It returns whether the number of execution slots changed.
+func (l *concurrencyLimiter) observe(rtt time.Duration, inflight int, dropped bool) bool {
+	before := l.Limit()
+
+	switch l.config.Algorithm {
+	case LimitVegas:
+		l.limit = l.vegas(rtt, inflight, dropped)
+	case LimitGradient:
+		l.limit = l.gradient(rtt, inflight, dropped)
+	default:
+		l.limit = l.aimd(inflight, dropped)
+	}
+	l.limit = l.bound(l.limit)
+
+	return l.Limit() != before
+}
+
+// appLimited returns whether too few commands were running to tell anything about the capacity of the dependency.
+func (l *concurrencyLimiter) appLimited(inflight int) bool {
+	return float64(inflight*2) < l.limit
+}
+
+func (l *concurrencyLimiter) aimd(inflight int, dropped bool) float64 {
+	if dropped {
+		ratio := l.config.BackoffRatio
+		if ratio <= 0 || ratio >= 1 {
+			ratio = DefaultLimitBackoffRatio
+		}
+		return l.limit * ratio
+	}
+	if l.appLimited(inflight) {
+		return l.limit
+	}
+	return l.limit + 1
+}
+
+func (l *concurrencyLimiter) vegas(rtt time.Duration, inflight int, dropped bool) float64 {
+	l.samples++
+	if l.samples >= vegasProbeSamples {
+		l.samples = 0
+		l.noLoadRTT = 0
+	}
+	if !dropped && rtt > 0 && (l.noLoadRTT == 0 || rtt < l.noLoadRTT) {
+		l.noLoadRTT = rtt
+	}
+
+	step := math.Max(1, math.Log10(l.limit))
+	if dropped {
+		return l.limit - step
+	}
+	if l.appLimited(inflight) || rtt <= 0 || l.noLoadRTT == 0 {
+		return l.limit
+	}
+
+	queued := l.limit * (1 - float64(l.noLoadRTT)/float64(rtt))
+	switch {
+	case queued < 3*step:
+		return l.limit + step
+	case queued > 6*step:
+		return l.limit - step
+	}
+	return l.limit
+}
+
+func (l *concurrencyLimiter) gradient(rtt time.Duration, inflight int, dropped bool) float64 {
+	gradient := 0.5
+	if !dropped {
+		if rtt <= 0 {
+			return l.limit
+		}
+		sample := float64(rtt)
+		if l.longRTT == 0 {
+			l.shortRTT = sample
+			l.longRTT = sample
+		}
+		l.shortRTT += (sample - l.shortRTT) / gradientShortWindow
+		l.longRTT += (sample - l.longRTT) / gradientLongWindow
+		// once commands got much faster the long run average catches up sooner, so that a later slowdown counts
+		if l.longRTT > 2*l.shortRTT {
+			l.longRTT *= 0.95
+		}
+		if l.appLimited(inflight) {
+			return l.limit
+		}
+		gradient = math.Max(0.5, math.Min(1, l.longRTT/l.shortRTT))
+	}
+
+	target := l.limit*gradient + math.Sqrt(l.limit)
+	return l.limit*(1-gradientSmoothing) + target*gradientSmoothing
+}
+
+func (l *concurrencyLimiter) bound(limit float64) float64 {
+	return math.Max(float64(l.min), math.Min(float64(l.max), limit))
+}
@@ -0,0 +1,114 @@
+package hystrix
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	newLimiter := func(algorithm LimitAlgorithm, initial int) *concurrencyLimiter {
+		return newConcurrencyLimiter(&Settings{
+			MaxConcurrentRequests: 100,
+			AdaptiveLimit:         &AdaptiveLimit{Algorithm: algorithm, InitialLimit: initial, MinLimit: 2},
+		})
+	}
+
+	Convey("given an AIMD limit of 20", t, func() {
+		l := newLimiter(LimitAIMD, 20)
+
+		Convey("a success of a busy pool raises it by one", func() {
+			So(l.observe(10*time.Millisecond, 10, false), ShouldBeTrue)
+			So(l.Limit(), ShouldEqual, 21)
+		})
+
+		Convey("a success of a pool hardly used leaves it", func() {
+			So(l.observe(10*time.Millisecond, 1, false), ShouldBeFalse)
+			So(l.Limit(), ShouldEqual, 20)
+		})
+
+		Convey("a failure multiplies it by the backoff ratio", func() {
+			So(l.observe(10*time.Millisecond, 10, true), ShouldBeTrue)
+			So(l.Limit(), ShouldEqual, 18)
+		})
+
+		Convey("it stays between MinLimit and MaxConcurrentRequests", func() {
+			for i := 0; i < 100; i++ {
+				l.observe(10*time.Millisecond, 100, true)
+			}
+			So(l.Limit(), ShouldEqual, 2)
+			for i := 0; i < 200; i++ {
+				l.observe(10*time.Millisecond, 100, false)
+			}
+			So(l.Limit(), ShouldEqual, 100)
+		})
+	})
+
+	Convey("given a Vegas limit of 20", t, func() {
+		l := newLimiter(LimitVegas, 20)
+		l.observe(10*time.Millisecond, 1, false)
+
+		Convey("commands as fast as the fastest raise it", func() {
+			l.observe(10*time.Millisecond, 20, false)
+			So(l.Limit(), ShouldEqual, 21)
+		})
+
+		Convey("commands much slower than the fastest lower it", func() {
+			l.observe(100*time.Millisecond, 20, false)
+			So(l.Limit(), ShouldEqual, 18)
+		})
+	})
+
+	Convey("given a gradient limit of 20", t, func() {
+		l := newLimiter(LimitGradient, 20)
+
+		Convey("steady run durations raise it", func() {
+			for i := 0; i < 10; i++ {
+				l.observe(10*time.Millisecond, 20, false)
+			}
+			So(l.Limit(), ShouldBeGreaterThan, 20)
+		})
+
+		Convey("slower run durations lower it", func() {
+			l.observe(10*time.Millisecond, 20, false)
+			for i := 0; i < 10; i++ {
+				l.observe(100*time.Millisecond, 20, false)
+			}
+			So(l.Limit(), ShouldBeLessThan, 20)
+		})
+	})
+}
+
+func TestAdaptiveLimit(t *testing.T) {
+	Convey("given a circuit with an AIMD limit", t, func() {
+		registry := NewRegistry()
+		defer registry.Flush()
+		registry.ConfigureCommand("adaptive", CommandConfig{MaxConcurrentRequests: 10, RequestVolumeThreshold: 1000})
+		registry.UpdateSettings("adaptive", func(s *Settings) {
+			s.AdaptiveLimit = &AdaptiveLimit{Algorithm: LimitAIMD, MinLimit: 2}
+		})
+		cb, _, _ := registry.GetCircuit("adaptive")
+
+		Convey("it starts at MaxConcurrentRequests", func() {
+			So(cb.ConcurrencyLimit(), ShouldEqual, 10)
+		})
+
+		Convey("failing commands shrink the executor pool", func() {
+			for i := 0; i < 5; i++ {
+				So(registry.Do("adaptive", func() error { return errors.New("overloaded") }, nil), ShouldNotBeNil)
+			}
+			for cb.Metrics().NumRequests().Sum(time.Now()) < 5 {
+				time.Sleep(time.Millisecond)
+			}
+			So(cb.ConcurrencyLimit(), ShouldBeLessThan, 10)
+			So(cb.ConcurrencyLimit(), ShouldBeGreaterThanOrEqualTo, 2)
+
+			Convey("and removing the adaptive limit restores MaxConcurrentRequests", func() {
+				registry.UpdateSettings("adaptive", func(s *Settings) { s.AdaptiveLimit = nil })
+				So(cb.ConcurrencyLimit(), ShouldEqual, 10)
+			})
+		})
+	})
+}
@@ -1,6 +1,7 @@
 package metricCollector
 
 import (
+	"log"
 	"sync"
 	"time"
 
@@ -16,6 +17,12 @@ import (
 type DefaultMetricCollector struct {
 	mutex *sync.RWMutex
 
+	window            time.Duration
+	buckets           int
+	percentileWindow  time.Duration
+	percentileBuckets int
+	distributions     DistributionFactory
+
 	numRequests *rolling.Number
 	errors      *rolling.Number
 
@@ -26,27 +33,107 @@ type DefaultMetricCollector struct {
 	shortCircuits *rolling.Number
 	timeouts      *rolling.Number
 
-	fallbackSuccesses *rolling.Number
-	fallbackFailures  *rolling.Number
-	totalDuration     *rolling.Timing
-	runDuration       *rolling.Timing
+	contextCanceled         *rolling.Number
+	contextDeadlineExceeded *rolling.Number
+
+	fallbackSuccesses  *rolling.Number
+	fallbackFailures   *rolling.Number
+	fallbackRejections *rolling.Number
+	hedges             *rolling.Number
+	totalDuration      rolling.Distribution
+	runDuration        rolling.Distribution
 }
 
-func newDefaultMetricCollector(name string, commandGroup string) MetricCollector {
-	m := &DefaultMetricCollector{}
-	m.mutex = &sync.RWMutex{}
-	m.Reset()
-	return m
+// DistributionFactory creates the rolling distributions in which a DefaultMetricCollector records
+// durations, covering window divided into the given number of buckets.
+type DistributionFactory func(window time.Duration, buckets int) rolling.Distribution
+
+// TimingDistributions records durations in a rolling.Timing, which keeps every duration of the window.
+// Percentiles are exact but their memory and cost grow with throughput. It is the default.
+func TimingDistributions(window time.Duration, buckets int) rolling.Distribution {
+	return rolling.NewTimingWithWindow(window, buckets)
 }
 
+// HistogramDistributions records durations in a rolling.Histogram, which uses bounded memory
+// and computes percentiles within 3% of the exact value.
+func HistogramDistributions(window time.Duration, buckets int) rolling.Distribution {
+	return rolling.NewHistogramWithWindow(window, buckets)
+}
+
+const (
+	defaultWindow            = 10 * time.Second
+	defaultBuckets           = 10
+	defaultPercentileWindow  = 60 * time.Second
+	defaultPercentileBuckets = 60
+)
+
 // New Create a new instance, note difference in signature
 func New(name string) *DefaultMetricCollector {
+	return NewWithDistributions(name, TimingDistributions)
+}
+
+// NewWithDistributions creates a new instance recording durations in the distributions created by factory.
+func NewWithDistributions(name string, factory DistributionFactory) *DefaultMetricCollector {
 	m := &DefaultMetricCollector{}
 	m.mutex = &sync.RWMutex{}
+	m.window = defaultWindow
+	m.buckets = defaultBuckets
+	m.percentileWindow = defaultPercentileWindow
+	m.percentileBuckets = defaultPercentileBuckets
+	m.distributions = factory
 	m.Reset()
 	return m
 }
 
+// SetRollingWindow changes the window and number of buckets of the rolling numbers and
+// resets all metrics. Non-positive values keep the current window.
+func (d *DefaultMetricCollector) SetRollingWindow(window time.Duration, buckets int) {
+	d.mutex.Lock()
+	if window > 0 && buckets > 0 {
+		d.window = window
+		d.buckets = buckets
+	}
+	d.mutex.Unlock()
+
+	d.Reset()
+}
+
+// SetRollingPercentileWindow changes the window and number of buckets of the rolling timings
+// used for percentiles and resets all metrics. Non-positive values keep the current window.
+func (d *DefaultMetricCollector) SetRollingPercentileWindow(window time.Duration, buckets int) {
+	d.mutex.Lock()
+	if window > 0 && buckets > 0 {
+		d.percentileWindow = window
+		d.percentileBuckets = buckets
+	}
+	d.mutex.Unlock()
+
+	d.Reset()
+}
+
+// SetDistributionFactory changes how durations are recorded and resets all metrics.
+func (d *DefaultMetricCollector) SetDistributionFactory(factory DistributionFactory) {
+	d.mutex.Lock()
+	d.distributions = factory
+	d.mutex.Unlock()
+
+	d.Reset()
+}
+
+// RollingWindow returns the window and number of buckets of the rolling numbers.
+func (d *DefaultMetricCollector) RollingWindow() (time.Duration, int) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.window, d.buckets
+}
+
+// RollingPercentileWindow returns the window and number of buckets of the rolling timings.
+func (d *DefaultMetricCollector) RollingPercentileWindow() (time.Duration, int) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.percentileWindow, d.percentileBuckets
+}
+
 // NumRequests returns the rolling number of requests
 func (d *DefaultMetricCollector) NumRequests() *rolling.Number {
 	d.mutex.RLock()
@@ -103,6 +190,20 @@ func (d *DefaultMetricCollector) Timeouts() *rolling.Number {
 	return d.timeouts
 }
 
+// ContextCanceled returns the rolling number of requests abandoned by a canceled context
+func (d *DefaultMetricCollector) ContextCanceled() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.contextCanceled
+}
+
+// ContextDeadlineExceeded returns the rolling number of requests abandoned by an expired context
+func (d *DefaultMetricCollector) ContextDeadlineExceeded() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.contextDeadlineExceeded
+}
+
 // FallbackSuccesses returns the rolling number of fallback successes
 func (d *DefaultMetricCollector) FallbackSuccesses() *rolling.Number {
 	d.mutex.RLock()
@@ -117,15 +218,60 @@ func (d *DefaultMetricCollector) FallbackFailures() *rolling.Number {
 	return d.fallbackFailures
 }
 
-// TotalDuration returns the rolling total duration
+// FallbackRejections returns the rolling number of fallback rejections
+func (d *DefaultMetricCollector) FallbackRejections() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.fallbackRejections
+}
+
+// Hedges returns the rolling number of hedged requests
+func (d *DefaultMetricCollector) Hedges() *rolling.Number {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.hedges
+}
+
+// TotalDuration returns the rolling total duration.
+//
+// Deprecated: TotalDuration returns nil when the distribution factory of the collector does not record
+// durations in a rolling.Timing, e.g. with HistogramDistributions. Use TotalDurationDistribution instead.
 func (d *DefaultMetricCollector) TotalDuration() *rolling.Timing {
+	return timingOf("TotalDuration", d.TotalDurationDistribution())
+}
+
+// RunDuration returns the rolling run duration.
+//
+// Deprecated: RunDuration returns nil when the distribution factory of the collector does not record
+// durations in a rolling.Timing, e.g. with HistogramDistributions. Use RunDurationDistribution instead.
+func (d *DefaultMetricCollector) RunDuration() *rolling.Timing {
+	return timingOf("RunDuration", d.RunDurationDistribution())
+}
+
+// nilTimingOnce limits the warning of timingOf to one per process.
+var nilTimingOnce sync.Once
+
+// timingOf returns distribution when it is a rolling.Timing, otherwise it warns that the deprecated accessor
+// with the given name returned nil.
+func timingOf(accessor string, distribution rolling.Distribution) *rolling.Timing {
+	timing, ok := distribution.(*rolling.Timing)
+	if !ok {
+		nilTimingOnce.Do(func() {
+			log.Printf("hystrix-go: deprecated %v returned nil as durations are not recorded in a rolling.Timing, use %vDistribution", accessor, accessor)
+		})
+	}
+	return timing
+}
+
+// TotalDurationDistribution returns the rolling total duration in the distribution of the collector
+func (d *DefaultMetricCollector) TotalDurationDistribution() rolling.Distribution {
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
 	return d.totalDuration
 }
 
-// RunDuration returns the rolling run duration
-func (d *DefaultMetricCollector) RunDuration() *rolling.Timing {
+// RunDurationDistribution returns the rolling run duration in the distribution of the collector
+func (d *DefaultMetricCollector) RunDurationDistribution() rolling.Distribution {
 	d.mutex.RLock()
 	defer d.mutex.RUnlock()
 	return d.runDuration
@@ -188,6 +334,20 @@ func (d *DefaultMetricCollector) IncrementTimeouts() {
 	d.timeouts.Increment(1)
 }
 
+// IncrementContextCanceled increments the number of requests abandoned by a canceled context in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementContextCanceled() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.contextCanceled.Increment(1)
+}
+
+// IncrementContextDeadlineExceeded increments the number of requests abandoned by an expired context in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementContextDeadlineExceeded() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.contextDeadlineExceeded.Increment(1)
+}
+
 // IncrementFallbackSuccesses increments the number of successful calls to the fallback function in the latest time bucket.
 func (d *DefaultMetricCollector) IncrementFallbackSuccesses() {
 	d.mutex.RLock()
@@ -202,6 +362,20 @@ func (d *DefaultMetricCollector) IncrementFallbackFailures() {
 	d.fallbackFailures.Increment(1)
 }
 
+// IncrementFallbackRejections increments the number of rejected calls to the fallback function in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementFallbackRejections() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.fallbackRejections.Increment(1)
+}
+
+// IncrementHedges increments the number of requests with a hedged attempt in the latest time bucket.
+func (d *DefaultMetricCollector) IncrementHedges() {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	d.hedges.Increment(1)
+}
+
 // UpdateTotalDuration updates the total amount of time this circuit has been running.
 func (d *DefaultMetricCollector) UpdateTotalDuration(timeSinceStart time.Duration) {
 	d.mutex.RLock()
@@ -221,16 +395,20 @@ func (d *DefaultMetricCollector) Reset() {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	d.numRequests = rolling.NewNumber()
-	d.errors = rolling.NewNumber()
-	d.successes = rolling.NewNumber()
-	d.rejects = rolling.NewNumber()
-	d.queueSize = rolling.NewNumber()
-	d.shortCircuits = rolling.NewNumber()
-	d.failures = rolling.NewNumber()
-	d.timeouts = rolling.NewNumber()
-	d.fallbackSuccesses = rolling.NewNumber()
-	d.fallbackFailures = rolling.NewNumber()
-	d.totalDuration = rolling.NewTiming()
-	d.runDuration = rolling.NewTiming()
+	d.numRequests = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.errors = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.successes = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.rejects = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.queueSize = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.shortCircuits = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.failures = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.timeouts = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.contextCanceled = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.contextDeadlineExceeded = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.fallbackSuccesses = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.fallbackFailures = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.fallbackRejections = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.hedges = rolling.NewNumberWithWindow(d.window, d.buckets)
+	d.totalDuration = d.distributions(d.percentileWindow, d.percentileBuckets)
+	d.runDuration = d.distributions(d.percentileWindow, d.percentileBuckets)
 }
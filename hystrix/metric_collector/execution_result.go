@@ -0,0 +1,183 @@
+package metricCollector
+
+import (
+	"time"
+)
+
+// EventType identifies something which happened during the execution of a command.
+type EventType int
+
+const (
+	// EventSuccess is reported when the run function returned without error.
+	EventSuccess EventType = iota + 1
+	// EventFailure is reported when the run function returned an error.
+	EventFailure
+	// EventTimeout is reported when the run function did not return in time.
+	EventTimeout
+	// EventShortCircuit is reported when the command was rejected by an open circuit.
+	EventShortCircuit
+	// EventRejected is reported when the command did not get an execution slot.
+	EventRejected
+	// EventQueued is reported when the command had to wait for an execution slot.
+	EventQueued
+	// EventContextCanceled is reported when the caller canceled the context of the command.
+	EventContextCanceled
+	// EventContextDeadlineExceeded is reported when the deadline of the context of the command passed.
+	EventContextDeadlineExceeded
+	// EventFallbackSuccess is reported when the fallback function returned without error.
+	EventFallbackSuccess
+	// EventFallbackFailure is reported when the fallback function returned an error.
+	EventFallbackFailure
+	// EventFallbackRejection is reported when the fallback function did not run because FallbackMaxConcurrentRequests
+	// fallbacks of the circuit were already running.
+	EventFallbackRejection
+	// EventHedged is reported when a second attempt of the run function was started because the first was slow.
+	EventHedged
+)
+
+var eventTypeNames = map[EventType]string{
+	EventSuccess:                 "success",
+	EventFailure:                 "failure",
+	EventTimeout:                 "timeout",
+	EventShortCircuit:            "short-circuit",
+	EventRejected:                "rejected",
+	EventQueued:                  "queued",
+	EventContextCanceled:         "context-canceled",
+	EventContextDeadlineExceeded: "context-deadline-exceeded",
+	EventFallbackSuccess:         "fallback-success",
+	EventFallbackFailure:         "fallback-failure",
+	EventFallbackRejection:       "fallback-rejection",
+	EventHedged:                  "hedged",
+}
+
+// String returns the name of the event, e.g. "short-circuit".
+func (e EventType) String() string {
+	if name, ok := eventTypeNames[e]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseEventType returns the EventType with the given name.
+func ParseEventType(name string) (EventType, bool) {
+	for e, n := range eventTypeNames {
+		if n == name {
+			return e, true
+		}
+	}
+	return 0, false
+}
+
+// ExecutionResult describes a finished execution of a command.
+type ExecutionResult struct {
+	// Events holds every event of the execution in the order they happened,
+	// e.g. EventQueued, EventTimeout and EventFallbackSuccess.
+	Events []EventType
+	// Start is when the command was started.
+	Start time.Time
+	// QueueWait is the time spent waiting for an execution slot.
+	QueueWait time.Duration
+	// RunDuration is the time spent in the run function, zero when it did not run or timed out.
+	RunDuration time.Duration
+	// TotalDuration is the time from the start of the command to its result.
+	TotalDuration time.Duration
+	// Err is the error returned to the caller, nil when the run or the fallback function succeeded.
+	Err error
+}
+
+// HasEvent returns whether the event happened during the execution.
+func (r *ExecutionResult) HasEvent(event EventType) bool {
+	for _, e := range r.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Fallback returns EventFallbackSuccess or EventFallbackFailure when the fallback function ran,
+// EventFallbackRejection when it was rejected, and zero otherwise.
+func (r *ExecutionResult) Fallback() EventType {
+	for _, e := range r.Events {
+		if e == EventFallbackSuccess || e == EventFallbackFailure || e == EventFallbackRejection {
+			return e
+		}
+	}
+	return 0
+}
+
+// ResultCollector is an optional extension of MetricCollector for collectors which record whole
+// executions rather than individual increments. CollectResult is called instead of the
+// Increment and Update methods for every finished execution.
+type ResultCollector interface {
+	MetricCollector
+	CollectResult(result *ExecutionResult)
+}
+
+// Collect records the result of an execution in collector, through CollectResult when it is a
+// ResultCollector and otherwise by calling its Increment and Update methods for every event.
+func Collect(collector MetricCollector, result *ExecutionResult) {
+	if rc, ok := collector.(ResultCollector); ok {
+		rc.CollectResult(result)
+		return
+	}
+
+	IncrementEvents(collector, result)
+}
+
+// IncrementEvents calls the Increment and Update methods of collector for every event of the result.
+// ResultCollectors can use it to keep their Increment methods up to date.
+func IncrementEvents(collector MetricCollector, result *ExecutionResult) {
+	for _, event := range result.Events {
+		switch event {
+		case EventSuccess:
+			collector.IncrementAttempts()
+			collector.IncrementSuccesses()
+		case EventFailure:
+			collector.IncrementFailures()
+
+			collector.IncrementAttempts()
+			collector.IncrementErrors()
+		case EventRejected:
+			collector.IncrementRejects()
+
+			collector.IncrementAttempts()
+			collector.IncrementErrors()
+		case EventShortCircuit:
+			collector.IncrementShortCircuits()
+
+			collector.IncrementAttempts()
+			collector.IncrementErrors()
+		case EventTimeout:
+			collector.IncrementTimeouts()
+
+			collector.IncrementAttempts()
+			collector.IncrementErrors()
+		case EventQueued:
+			collector.IncrementQueueSize()
+		case EventHedged:
+			if hc, ok := collector.(HedgeCollector); ok {
+				hc.IncrementHedges()
+			}
+		// the caller abandoning a command says nothing about the health of the circuit,
+		// so these are neither counted as attempts nor as errors
+		case EventContextCanceled:
+			if cc, ok := collector.(ContextCollector); ok {
+				cc.IncrementContextCanceled()
+			}
+		case EventContextDeadlineExceeded:
+			if cc, ok := collector.(ContextCollector); ok {
+				cc.IncrementContextDeadlineExceeded()
+			}
+		case EventFallbackSuccess:
+			collector.IncrementFallbackSuccesses()
+		case EventFallbackFailure:
+			collector.IncrementFallbackFailures()
+		case EventFallbackRejection:
+			collector.IncrementFallbackRejections()
+		}
+	}
+
+	collector.UpdateTotalDuration(result.TotalDuration)
+	collector.UpdateRunDuration(result.RunDuration)
+}
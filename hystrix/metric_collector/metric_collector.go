@@ -5,22 +5,46 @@ import (
 	"time"
 )
 
-// Registry is the default metricCollectorRegistry that circuits will use to
+// Registry is the default MetricCollectorRegistry that circuits will use to
 // collect statistics about the health of the circuit.
-var Registry = metricCollectorRegistry{
-	lock: &sync.RWMutex{},
-	registry: []func(name string, commandGroup string) MetricCollector{
-		newDefaultMetricCollector,
-	},
+var Registry = NewMetricCollectorRegistry()
+
+// MetricCollectorRegistry holds the MetricCollector initializers used when new circuits are created.
+type MetricCollectorRegistry struct {
+	lock          *sync.RWMutex
+	registry      []func(name string, commandGroup string) MetricCollector
+	distributions DistributionFactory
+}
+
+// NewMetricCollectorRegistry creates a MetricCollectorRegistry holding only the DefaultMetricCollector,
+// which must remain the first registered collector.
+func NewMetricCollectorRegistry() *MetricCollectorRegistry {
+	m := &MetricCollectorRegistry{
+		lock:          &sync.RWMutex{},
+		distributions: TimingDistributions,
+	}
+	m.registry = []func(name string, commandGroup string) MetricCollector{
+		m.newDefaultMetricCollector,
+	}
+	return m
+}
+
+// newDefaultMetricCollector is always called with the lock held by InitializeMetricCollectors.
+func (m *MetricCollectorRegistry) newDefaultMetricCollector(name string, commandGroup string) MetricCollector {
+	return NewWithDistributions(name, m.distributions)
 }
 
-type metricCollectorRegistry struct {
-	lock     *sync.RWMutex
-	registry []func(name string, commandGroup string) MetricCollector
+// SetDistributionFactory changes how the DefaultMetricCollector of circuits created afterwards records durations,
+// for instance to HistogramDistributions.
+func (m *MetricCollectorRegistry) SetDistributionFactory(factory DistributionFactory) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.distributions = factory
 }
 
 // InitializeMetricCollectors runs the registried MetricCollector Initializers to create an array of MetricCollectors.
-func (m *metricCollectorRegistry) InitializeMetricCollectors(name string, commandGroup string) []MetricCollector {
+func (m *MetricCollectorRegistry) InitializeMetricCollectors(name string, commandGroup string) []MetricCollector {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
 
@@ -31,8 +55,8 @@ func (m *metricCollectorRegistry) InitializeMetricCollectors(name string, comman
 	return metrics
 }
 
-// Register places a MetricCollector Initializer in the registry maintained by this metricCollectorRegistry.
-func (m *metricCollectorRegistry) Register(initMetricCollector func(string, string) MetricCollector) {
+// Register places a MetricCollector Initializer in the registry maintained by this MetricCollectorRegistry.
+func (m *MetricCollectorRegistry) Register(initMetricCollector func(string, string) MetricCollector) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -65,6 +89,8 @@ type MetricCollector interface {
 	IncrementFallbackSuccesses()
 	// IncrementFallbackFailures increments the number of failures that occurred during the execution of the fallback function.
 	IncrementFallbackFailures()
+	// IncrementFallbackRejections increments the number of fallbacks which were rejected because too many were running.
+	IncrementFallbackRejections()
 	// UpdateTotalDuration updates the internal counter of how long we've run for.
 	UpdateTotalDuration(timeSinceStart time.Duration)
 	// UpdateRunDuration updates the internal counter of how long the last run took.
@@ -72,3 +98,29 @@ type MetricCollector interface {
 	// Reset resets the internal counters and timers.
 	Reset()
 }
+
+// ContextCollector is an optional extension of MetricCollector for collectors which count the commands abandoned
+// by their caller, the other collectors ignore EventContextCanceled and EventContextDeadlineExceeded.
+type ContextCollector interface {
+	MetricCollector
+	// IncrementContextCanceled increments the number of requests abandoned because the caller's context was canceled.
+	IncrementContextCanceled()
+	// IncrementContextDeadlineExceeded increments the number of requests abandoned because the caller's context deadline passed.
+	IncrementContextDeadlineExceeded()
+}
+
+// HedgeCollector is an optional extension of MetricCollector for collectors which count hedged commands,
+// the other collectors ignore EventHedged.
+type HedgeCollector interface {
+	MetricCollector
+	// IncrementHedges increments the number of requests for which a second, hedged attempt was started.
+	IncrementHedges()
+}
+
+// ReleasingCollector is an optional extension of MetricCollector for collectors which keep series of their
+// circuit outside of the collector, e.g. in a Prometheus registry. Release is called once the circuit is
+// discarded, such as the circuit of an evicted key, and should drop them. The collector is not used afterwards.
+type ReleasingCollector interface {
+	MetricCollector
+	Release()
+}
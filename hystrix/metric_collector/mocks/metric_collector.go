@@ -14,6 +14,16 @@ func (_m *MetricCollector) IncrementAttempts() {
 	_m.Called()
 }
 
+// IncrementContextCanceled provides a mock function with given fields:
+func (_m *MetricCollector) IncrementContextCanceled() {
+	_m.Called()
+}
+
+// IncrementContextDeadlineExceeded provides a mock function with given fields:
+func (_m *MetricCollector) IncrementContextDeadlineExceeded() {
+	_m.Called()
+}
+
 // IncrementErrors provides a mock function with given fields:
 func (_m *MetricCollector) IncrementErrors() {
 	_m.Called()
@@ -29,11 +39,21 @@ func (_m *MetricCollector) IncrementFallbackFailures() {
 	_m.Called()
 }
 
+// IncrementFallbackRejections provides a mock function with given fields:
+func (_m *MetricCollector) IncrementFallbackRejections() {
+	_m.Called()
+}
+
 // IncrementFallbackSuccesses provides a mock function with given fields:
 func (_m *MetricCollector) IncrementFallbackSuccesses() {
 	_m.Called()
 }
 
+// IncrementHedges provides a mock function with given fields:
+func (_m *MetricCollector) IncrementHedges() {
+	_m.Called()
+}
+
 // IncrementQueueSize provides a mock function with given fields:
 func (_m *MetricCollector) IncrementQueueSize() {
 	_m.Called()
@@ -8,28 +8,33 @@ import (
 	"github.com/myteksi/hystrix-go/hystrix/rolling"
 )
 
-type commandExecution struct {
-	Types       []string      `json:"types"`
-	Start       time.Time     `json:"start_time"`
-	RunDuration time.Duration `json:"run_duration"`
-}
-
 type metricExchange struct {
-	Name    string
-	Updates chan *commandExecution
+	Name string
+	// command is the name of the command whose settings apply, which differs from Name for keyed circuits
+	command  string
+	registry *Registry
+
+	Updates chan *ExecutionResult
 	Mutex   *sync.RWMutex
+	// done is closed once the updates are no longer monitored
+	done chan struct{}
 
 	metricCollectors []metricCollector.MetricCollector
 }
 
-func newMetricExchange(name string, commandGroup string) *metricExchange {
+// newMetricExchange creates the metrics of the circuit with the given name, for the command described by settings.
+func newMetricExchange(registry *Registry, name string, settings *Settings) *metricExchange {
 	m := &metricExchange{}
 	m.Name = name
+	m.command = settings.CommandName
+	m.registry = registry
 
-	m.Updates = make(chan *commandExecution, 2000)
+	m.Updates = make(chan *ExecutionResult, 2000)
 	m.Mutex = &sync.RWMutex{}
-	m.metricCollectors = metricCollector.Registry.InitializeMetricCollectors(name, commandGroup)
+	m.done = make(chan struct{})
+	m.metricCollectors = registry.metricCollectors.InitializeMetricCollectors(name, settings.CommandGroup)
 	m.Reset()
+	m.setRollingWindows(settings)
 
 	go m.Monitor()
 
@@ -49,68 +54,40 @@ func (m *metricExchange) DefaultCollector() *metricCollector.DefaultMetricCollec
 }
 
 func (m *metricExchange) Monitor() {
-	for update := range m.Updates {
-		// we only grab a read lock to make sure Reset() isn't changing the numbers.
-		m.Mutex.RLock()
-
-		totalDuration := time.Since(update.Start)
-		wg := &sync.WaitGroup{}
-		for _, collector := range m.metricCollectors {
-			wg.Add(1)
-			go m.IncrementMetrics(wg, collector, update, totalDuration)
+	for {
+		select {
+		case update := <-m.Updates:
+			// we only grab a read lock to make sure Reset() isn't changing the numbers.
+			m.Mutex.RLock()
+
+			wg := &sync.WaitGroup{}
+			for _, collector := range m.metricCollectors {
+				wg.Add(1)
+				go m.IncrementMetrics(wg, collector, update)
+			}
+			wg.Wait()
+
+			m.Mutex.RUnlock()
+		case <-m.done:
+			return
 		}
-		wg.Wait()
-
-		m.Mutex.RUnlock()
 	}
 }
 
-func (m *metricExchange) IncrementMetrics(wg *sync.WaitGroup, collector metricCollector.MetricCollector, update *commandExecution, totalDuration time.Duration) {
-	// granular metrics
-	if update.Types[0] == "success" {
-		collector.IncrementAttempts()
-		collector.IncrementSuccesses()
-	}
-	if update.Types[0] == "failure" {
-		collector.IncrementFailures()
-
-		collector.IncrementAttempts()
-		collector.IncrementErrors()
-	}
-	if update.Types[0] == "rejected" {
-		collector.IncrementRejects()
+// Stop ends the monitor and releases the collectors which are ReleasingCollectors, later updates are never collected.
+func (m *metricExchange) Stop() {
+	close(m.done)
 
-		collector.IncrementAttempts()
-		collector.IncrementErrors()
-	}
-	if update.Types[0] == "short-circuit" {
-		collector.IncrementShortCircuits()
-
-		collector.IncrementAttempts()
-		collector.IncrementErrors()
-	}
-	if update.Types[0] == "timeout" {
-		collector.IncrementTimeouts()
-
-		collector.IncrementAttempts()
-		collector.IncrementErrors()
-	}
-	if update.Types[0] == "queued" {
-		collector.IncrementQueueSize()
-	}
-
-	if len(update.Types) > 1 {
-		// fallback metrics
-		if update.Types[1] == "fallback-success" {
-			collector.IncrementFallbackSuccesses()
-		}
-		if update.Types[1] == "fallback-failure" {
-			collector.IncrementFallbackFailures()
+	for _, collector := range m.metricCollectors {
+		if rc, ok := collector.(metricCollector.ReleasingCollector); ok {
+			rc.Release()
 		}
 	}
+}
 
-	collector.UpdateTotalDuration(totalDuration)
-	collector.UpdateRunDuration(update.RunDuration)
+// IncrementMetrics records every event of an execution in collector.
+func (m *metricExchange) IncrementMetrics(wg *sync.WaitGroup, collector metricCollector.MetricCollector, update *ExecutionResult) {
+	metricCollector.Collect(collector, update)
 
 	wg.Done()
 }
@@ -124,6 +101,24 @@ func (m *metricExchange) Reset() {
 	}
 }
 
+// setRollingWindows applies the rolling windows of the settings to the default collector,
+// its metrics are only reset when a window changes.
+func (m *metricExchange) setRollingWindows(s *Settings) {
+	m.Mutex.Lock()
+	defer m.Mutex.Unlock()
+
+	collector := m.DefaultCollector()
+	window, buckets := s.rollingWindow()
+	if currentWindow, currentBuckets := collector.RollingWindow(); currentWindow != window || currentBuckets != buckets {
+		collector.SetRollingWindow(window, buckets)
+	}
+
+	window, buckets = s.rollingPercentileWindow()
+	if currentWindow, currentBuckets := collector.RollingPercentileWindow(); currentWindow != window || currentBuckets != buckets {
+		collector.SetRollingPercentileWindow(window, buckets)
+	}
+}
+
 func (m *metricExchange) Requests() *rolling.Number {
 	m.Mutex.RLock()
 	defer m.Mutex.RUnlock()
@@ -150,5 +145,5 @@ func (m *metricExchange) ErrorPercent(now time.Time) int {
 }
 
 func (m *metricExchange) IsHealthy(now time.Time) bool {
-	return m.ErrorPercent(now) < getSettings(m.Name).ErrorPercentThreshold
+	return m.ErrorPercent(now) < m.registry.getSettings(m.command).ErrorPercentThreshold
 }
@@ -1,20 +1,22 @@
 package hystrix
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
 func metricFailingPercent(p int) *metricExchange {
-	m := newMetricExchange("", "")
+	m := newMetricExchange(defaultRegistry, "", getSettings(""))
 	for i := 0; i < 100; i++ {
-		t := "success"
+		t := EventSuccess
 		if i < p {
-			t = "failure"
+			t = EventFailure
 		}
-		m.Updates <- &commandExecution{Types: []string{t}}
+		m.Updates <- &ExecutionResult{Events: []EventType{t}}
 	}
 
 	// Updates needs to be flushed
@@ -43,3 +45,123 @@ func TestErrorPercent(t *testing.T) {
 		})
 	})
 }
+
+func TestMetricsRollingWindow(t *testing.T) {
+	Convey("with a circuit configured for a rolling window of 100ms", t, func() {
+		defer Flush()
+		ConfigureCommand("window", CommandConfig{MetricsRollingWindow: 100, MetricsRollingBuckets: 2})
+		cb, _, _ := GetCircuit("window")
+
+		Convey("the default collector uses the window", func() {
+			window, buckets := cb.metrics.DefaultCollector().RollingWindow()
+			So(window, ShouldEqual, 100*time.Millisecond)
+			So(buckets, ShouldEqual, 2)
+		})
+
+		Convey("failures are forgotten once the window has passed", func() {
+			cb.metrics.Updates <- &ExecutionResult{Events: []EventType{EventFailure}}
+			time.Sleep(10 * time.Millisecond)
+			So(cb.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 1)
+
+			time.Sleep(200 * time.Millisecond)
+			So(cb.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 0)
+		})
+
+		Convey("changing the window at runtime applies it", func() {
+			UpdateSettings("window", func(s *Settings) {
+				s.MetricsRollingWindow = time.Second
+				s.MetricsRollingBuckets = 4
+			})
+			window, buckets := cb.metrics.DefaultCollector().RollingWindow()
+			So(window, ShouldEqual, time.Second)
+			So(buckets, ShouldEqual, 4)
+		})
+	})
+}
+
+func TestIncrementMetrics(t *testing.T) {
+	Convey("with a command which was queued and then succeeded", t, func() {
+		m := newMetricExchange(defaultRegistry, "", getSettings(""))
+		m.Updates <- &ExecutionResult{Events: []EventType{EventQueued, EventSuccess}}
+		time.Sleep(100 * time.Millisecond)
+		collector := m.DefaultCollector()
+
+		Convey("every event is counted", func() {
+			So(collector.QueueSize().Sum(time.Now()), ShouldEqual, 1)
+			So(collector.NumRequests().Sum(time.Now()), ShouldEqual, 1)
+			So(collector.Successes().Sum(time.Now()), ShouldEqual, 1)
+		})
+	})
+
+	Convey("with a command which was queued, timed out and fell back", t, func() {
+		m := newMetricExchange(defaultRegistry, "", getSettings(""))
+		m.Updates <- &ExecutionResult{Events: []EventType{EventQueued, EventRejected, EventFallbackSuccess}}
+		time.Sleep(100 * time.Millisecond)
+		collector := m.DefaultCollector()
+
+		Convey("the rejection is an error and the fallback is counted", func() {
+			So(collector.Rejects().Sum(time.Now()), ShouldEqual, 1)
+			So(collector.Errors().Sum(time.Now()), ShouldEqual, 1)
+			So(collector.FallbackSuccesses().Sum(time.Now()), ShouldEqual, 1)
+		})
+	})
+}
+
+// resultCollector records the results it receives.
+type resultCollector struct {
+	metricCollector.MetricCollector
+	results chan *ExecutionResult
+}
+
+func (c *resultCollector) CollectResult(result *ExecutionResult) {
+	c.results <- result
+}
+
+func TestResultCollector(t *testing.T) {
+	Convey("given a registry with a ResultCollector", t, func() {
+		registry := NewRegistry()
+		collector := &resultCollector{MetricCollector: metricCollector.New("result"), results: make(chan *ExecutionResult, 1)}
+		registry.MetricCollectors().Register(func(name string, commandGroup string) metricCollector.MetricCollector {
+			return collector
+		})
+
+		Convey("it receives the whole result of an execution", func() {
+			err := registry.Do("result", func() error {
+				return fmt.Errorf("failed")
+			}, func(err error) error {
+				return nil
+			})
+			So(err, ShouldBeNil)
+
+			result := <-collector.results
+			So(result.Events, ShouldResemble, []EventType{EventFailure, EventFallbackSuccess})
+			So(result.Fallback(), ShouldEqual, EventFallbackSuccess)
+			So(result.Err, ShouldBeNil)
+			So(result.TotalDuration, ShouldBeGreaterThan, 0)
+		})
+	})
+}
+
+func TestDurationDistributions(t *testing.T) {
+	Convey("given a collector recording durations in timings", t, func() {
+		collector := metricCollector.New("timings")
+		collector.UpdateRunDuration(10 * time.Millisecond)
+
+		Convey("they are returned as timings and as distributions", func() {
+			So(collector.RunDuration().Mean(), ShouldEqual, 10)
+			So(collector.RunDurationDistribution().Mean(), ShouldEqual, 10)
+			So(collector.TotalDuration(), ShouldNotBeNil)
+		})
+	})
+
+	Convey("given a collector recording durations in histograms", t, func() {
+		collector := metricCollector.NewWithDistributions("histograms", metricCollector.HistogramDistributions)
+		collector.UpdateRunDuration(10 * time.Millisecond)
+
+		Convey("they are only returned as distributions, the deprecated timings being nil", func() {
+			So(collector.RunDuration(), ShouldBeNil)
+			So(collector.TotalDuration(), ShouldBeNil)
+			So(collector.RunDurationDistribution().PercentileDuration(50), ShouldBeGreaterThan, 0)
+		})
+	})
+}
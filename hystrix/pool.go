@@ -14,17 +14,27 @@ type bufferedExecutorPool struct {
 	WaitingTicket               chan *struct{}
 	Tickets                     chan *struct{}
 
+	// tickets which are still held by commands but no longer fit after the pool shrank,
+	// they are discarded instead of being returned.
+	retiredTickets        int
+	retiredWaitingTickets int
+	// resized is closed and replaced whenever the ticket channels are replaced, waking up queued commands.
+	resized chan struct{}
+
 	mutex sync.Mutex
 }
 
-func newBufferedExecutorPool(name string) *bufferedExecutorPool {
+// newBufferedExecutorPool creates the pool with the given name, sized by settings.
+func newBufferedExecutorPool(name string, settings *Settings) *bufferedExecutorPool {
 	p := &bufferedExecutorPool{}
 	p.Name = name
 	p.mutex = sync.Mutex{}
-	p.Metrics = newBufferedPoolMetrics(name)
-	p.Max = getSettings(name).MaxConcurrentRequests
-	p.QueueSizeRejectionThreshold = getSettings(name).QueueSizeRejectionThreshold
+	window, buckets := settings.rollingWindow()
+	p.Metrics = newBufferedPoolMetrics(p.Name, window, buckets)
+	p.Max = settings.MaxConcurrentRequests
+	p.QueueSizeRejectionThreshold = settings.QueueSizeRejectionThreshold
 	p.WaitingTicket = make(chan *struct{}, p.QueueSizeRejectionThreshold)
+	p.resized = make(chan struct{})
 
 	p.Tickets = make(chan *struct{}, p.Max)
 	for i := 0; i < p.Max; i++ {
@@ -37,14 +47,106 @@ func newBufferedExecutorPool(name string) *bufferedExecutorPool {
 	return p
 }
 
+// Resize changes the number of execution and waiting tickets of a pool which may be in use.
+// Tickets held by running commands stay valid, when the pool shrinks they are discarded
+// on return until the pool is back to its new size.
+func (p *bufferedExecutorPool) Resize(max int, queueSizeRejectionThreshold int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if max == p.Max && queueSizeRejectionThreshold == p.QueueSizeRejectionThreshold {
+		return
+	}
+
+	p.Tickets, p.retiredTickets = resizeTickets(p.Tickets, p.Max, p.retiredTickets, max)
+	p.Max = max
+	p.WaitingTicket, p.retiredWaitingTickets = resizeTickets(p.WaitingTicket, p.QueueSizeRejectionThreshold, p.retiredWaitingTickets, queueSizeRejectionThreshold)
+	p.QueueSizeRejectionThreshold = queueSizeRejectionThreshold
+
+	close(p.resized)
+	p.resized = make(chan struct{})
+}
+
+// resizeTickets moves the available tickets of a channel sized for current tickets into a new
+// channel sized for target tickets, and returns it with the number of tickets to retire.
+func resizeTickets(tickets chan *struct{}, current int, retired int, target int) (chan *struct{}, int) {
+	available := 0
+drain:
+	for {
+		select {
+		case <-tickets:
+			available++
+		default:
+			break drain
+		}
+	}
+
+	// tickets held by commands are current+retired-available, once they are all returned
+	// the new channel has to hold exactly target tickets.
+	refill := available + target - current - retired
+	retired = 0
+	if refill < 0 {
+		retired = -refill
+		refill = 0
+	}
+
+	resized := make(chan *struct{}, target)
+	for i := 0; i < refill; i++ {
+		resized <- &struct{}{}
+	}
+
+	return resized, retired
+}
+
+// tickets returns the current execution ticket channel, along with a channel which is closed
+// once it has been replaced.
+func (p *bufferedExecutorPool) tickets() (chan *struct{}, chan struct{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.Tickets, p.resized
+}
+
+func (p *bufferedExecutorPool) waitingTickets() chan *struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.WaitingTicket
+}
+
+// WaitTicket blocks until an execution ticket becomes available, following the pool through resizes.
+// It returns nil when either cancel channel is closed first.
+func (p *bufferedExecutorPool) WaitTicket(timeout <-chan struct{}, done <-chan struct{}) *struct{} {
+	for {
+		tickets, resized := p.tickets()
+		select {
+		case t := <-tickets:
+			return t
+		case <-resized:
+		case <-timeout:
+			return nil
+		case <-done:
+			return nil
+		}
+	}
+}
+
 func (p *bufferedExecutorPool) Return(ticket *struct{}) {
 	if ticket == nil {
 		return
 	}
 
-	p.Metrics.Updates <- bufferedPoolMetricsUpdate{
+	p.Metrics.update(bufferedPoolMetricsUpdate{
 		activeCount:  p.ActiveCount(),
 		waitingCount: p.WaitingCount(),
+	})
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.retiredTickets > 0 {
+		p.retiredTickets--
+		return
 	}
 	p.Tickets <- ticket
 }
@@ -54,13 +156,34 @@ func (p *bufferedExecutorPool) ReturnWaitingTicket(ticket *struct{}) {
 		return
 	}
 
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.retiredWaitingTickets > 0 {
+		p.retiredWaitingTickets--
+		return
+	}
 	p.WaitingTicket <- ticket
 }
 
 func (p *bufferedExecutorPool) ActiveCount() int {
-	return p.Max - len(p.Tickets)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.Max + p.retiredTickets - len(p.Tickets)
 }
 
 func (p *bufferedExecutorPool) WaitingCount() int {
-	return p.QueueSizeRejectionThreshold - len(p.WaitingTicket)
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.QueueSizeRejectionThreshold + p.retiredWaitingTickets - len(p.WaitingTicket)
+}
+
+// Size returns the current number of execution tickets and waiting tickets of the pool.
+func (p *bufferedExecutorPool) Size() (int, int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.Max, p.QueueSizeRejectionThreshold
 }
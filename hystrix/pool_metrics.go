@@ -2,6 +2,7 @@ package hystrix
 
 import (
 	"sync"
+	"time"
 
 	"github.com/myteksi/hystrix-go/hystrix/rolling"
 )
@@ -9,8 +10,12 @@ import (
 type bufferedPoolMetrics struct {
 	Mutex   *sync.RWMutex
 	Updates chan bufferedPoolMetricsUpdate
+	// done is closed once the metrics are no longer monitored
+	done chan struct{}
 
 	Name               string
+	Window             time.Duration
+	Buckets            int
 	MaxActiveRequests  *rolling.Number
 	MaxWaitingRequests *rolling.Number
 	Executed           *rolling.Number
@@ -21,10 +26,13 @@ type bufferedPoolMetricsUpdate struct {
 	waitingCount int
 }
 
-func newBufferedPoolMetrics(name string) *bufferedPoolMetrics {
+func newBufferedPoolMetrics(name string, window time.Duration, buckets int) *bufferedPoolMetrics {
 	m := &bufferedPoolMetrics{}
 	m.Name = name
+	m.Window = window
+	m.Buckets = buckets
 	m.Updates = make(chan bufferedPoolMetricsUpdate)
+	m.done = make(chan struct{})
 	m.Mutex = &sync.RWMutex{}
 
 	m.Reset()
@@ -38,19 +46,50 @@ func (m *bufferedPoolMetrics) Reset() {
 	m.Mutex.Lock()
 	defer m.Mutex.Unlock()
 
-	m.MaxActiveRequests = rolling.NewNumber()
-	m.MaxWaitingRequests = rolling.NewNumber()
-	m.Executed = rolling.NewNumber()
+	m.MaxActiveRequests = rolling.NewNumberWithWindow(m.Window, m.Buckets)
+	m.MaxWaitingRequests = rolling.NewNumberWithWindow(m.Window, m.Buckets)
+	m.Executed = rolling.NewNumberWithWindow(m.Window, m.Buckets)
+}
+
+// SetRollingWindow changes the window of the rolling numbers, resetting them when it differs from the current one.
+func (m *bufferedPoolMetrics) SetRollingWindow(window time.Duration, buckets int) {
+	m.Mutex.Lock()
+	changed := window != m.Window || buckets != m.Buckets
+	m.Window = window
+	m.Buckets = buckets
+	m.Mutex.Unlock()
+
+	if changed {
+		m.Reset()
+	}
 }
 
 func (m *bufferedPoolMetrics) Monitor() {
-	for u := range m.Updates {
-		m.Mutex.RLock()
+	for {
+		select {
+		case u := <-m.Updates:
+			m.Mutex.RLock()
 
-		m.Executed.Increment(1)
-		m.MaxActiveRequests.UpdateMax(float64(u.activeCount))
-		m.MaxWaitingRequests.UpdateMax(float64(u.waitingCount))
+			m.Executed.Increment(1)
+			m.MaxActiveRequests.UpdateMax(float64(u.activeCount))
+			m.MaxWaitingRequests.UpdateMax(float64(u.waitingCount))
 
-		m.Mutex.RUnlock()
+			m.Mutex.RUnlock()
+		case <-m.done:
+			return
+		}
 	}
 }
+
+// update sends u to the monitor, updates of stopped metrics are dropped.
+func (m *bufferedPoolMetrics) update(u bufferedPoolMetricsUpdate) {
+	select {
+	case m.Updates <- u:
+	case <-m.done:
+	}
+}
+
+// Stop ends the monitor of the metrics.
+func (m *bufferedPoolMetrics) Stop() {
+	close(m.done)
+}
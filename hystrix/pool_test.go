@@ -13,7 +13,7 @@ func TestReturn(t *testing.T) {
 	defer Flush()
 
 	Convey("when returning a ticket to the pool", t, func() {
-		pool := newBufferedExecutorPool("pool")
+		pool := newBufferedExecutorPool("pool", getSettings("pool"))
 		ticket := <-pool.Tickets
 		pool.Return(ticket)
 		time.Sleep(1 * time.Millisecond)
@@ -27,7 +27,7 @@ func TestActiveCount(t *testing.T) {
 	defer Flush()
 
 	Convey("when 3 tickets are pulled", t, func() {
-		pool := newBufferedExecutorPool("pool")
+		pool := newBufferedExecutorPool("pool", getSettings("pool"))
 		<-pool.Tickets
 		<-pool.Tickets
 		ticket := <-pool.Tickets
@@ -53,7 +53,7 @@ func TestWaitingCount(t *testing.T) {
 	ConfigureCommand("pool", CommandConfig{QueueSizeRejectionThreshold: 50})
 	Convey("when all execution tickets are pulled and then replenished", t, func() {
 
-		pool := newBufferedExecutorPool("pool")
+		pool := newBufferedExecutorPool("pool", getSettings("pool"))
 		checkpoint := make(chan struct{}, 1)
 		completedTask := int32(0)
 		// take away all pool tickets
@@ -104,7 +104,7 @@ func TestRefillTicket(t *testing.T) {
 	ConfigureCommand("pool", CommandConfig{QueueSizeRejectionThreshold: 50})
 	Convey("when all execution tickets are pulled and then replenished twice", t, func() {
 
-		pool := newBufferedExecutorPool("pool")
+		pool := newBufferedExecutorPool("pool", getSettings("pool"))
 		checkpoint1 := make(chan struct{}, 1)
 		checkpoint2 := make(chan struct{}, 1)
 		completedTask := int32(0)
@@ -156,3 +156,64 @@ func TestRefillTicket(t *testing.T) {
 		})
 	})
 }
+
+func TestResize(t *testing.T) {
+	defer Flush()
+
+	ConfigureCommand("pool", CommandConfig{MaxConcurrentRequests: 4, QueueSizeRejectionThreshold: 2})
+	Convey("with a pool of 4 with 3 tickets in use", t, func() {
+		pool := newBufferedExecutorPool("pool", getSettings("pool"))
+		t1 := <-pool.Tickets
+		t2 := <-pool.Tickets
+		t3 := <-pool.Tickets
+
+		Convey("when it grows to 6", func() {
+			pool.Resize(6, 2)
+
+			Convey("the tickets in use are kept and 3 are available", func() {
+				So(pool.ActiveCount(), ShouldEqual, 3)
+				So(len(pool.Tickets), ShouldEqual, 3)
+
+				Convey("and returning them makes all 6 available", func() {
+					pool.Return(t1)
+					pool.Return(t2)
+					pool.Return(t3)
+					So(pool.ActiveCount(), ShouldEqual, 0)
+					So(len(pool.Tickets), ShouldEqual, 6)
+				})
+			})
+		})
+
+		Convey("when it shrinks to 2", func() {
+			pool.Resize(2, 1)
+
+			Convey("no ticket is available while 3 are in use", func() {
+				So(pool.ActiveCount(), ShouldEqual, 3)
+				So(len(pool.Tickets), ShouldEqual, 0)
+				So(pool.WaitingCount(), ShouldEqual, 0)
+
+				Convey("and only 2 are available once all are returned", func() {
+					pool.Return(t1)
+					So(len(pool.Tickets), ShouldEqual, 0)
+					pool.Return(t2)
+					pool.Return(t3)
+					So(pool.ActiveCount(), ShouldEqual, 0)
+					So(len(pool.Tickets), ShouldEqual, 2)
+				})
+			})
+		})
+
+		Convey("a command waiting for a ticket gets one after the pool grows", func() {
+			<-pool.Tickets
+			acquired := make(chan *struct{}, 1)
+			go func() {
+				acquired <- pool.WaitTicket(nil, nil)
+			}()
+			time.Sleep(10 * time.Millisecond)
+			pool.Resize(5, 2)
+
+			So(<-acquired, ShouldNotBeNil)
+			So(pool.ActiveCount(), ShouldEqual, 5)
+		})
+	})
+}
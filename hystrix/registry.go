@@ -0,0 +1,80 @@
+package hystrix
+
+import (
+	"sync"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+)
+
+// Registry owns a set of circuits together with their settings and metric collectors.
+// Circuits of different registries never share state, which allows independent libraries
+// in one binary, or parallel tests, to use the same command names without interfering.
+//
+// The package level functions such as Go, Do and GetCircuit operate on a default registry.
+type Registry struct {
+	circuitBreakersMutex *sync.RWMutex
+	circuitBreakers      map[string]*CircuitBreaker
+
+	settingsMutex   *sync.RWMutex
+	circuitSettings map[string]*Settings
+
+	// applySettingsMutex serializes storing and applying settings updates
+	applySettingsMutex *sync.Mutex
+
+	metricCollectors *metricCollector.MetricCollectorRegistry
+
+	interceptorsMutex   *sync.RWMutex
+	interceptors        interceptors
+	commandInterceptors map[string]interceptors
+
+	stateListenersMutex *sync.RWMutex
+	stateListeners      []func(StateChange)
+
+	threadPoolsMutex *sync.Mutex
+	threadPools      map[string]*threadPool
+
+	retryBudgetsMutex *sync.Mutex
+	retryBudgets      map[retryBudgetKey]*retryBudget
+
+	keyedCircuitsMutex *sync.RWMutex
+	keyedCircuits      map[string]*keyedCircuits
+}
+
+var defaultRegistry = newRegistry(metricCollector.Registry)
+
+// NewRegistry creates an empty Registry which collects metrics with the DefaultMetricCollector only.
+// Additional collectors can be registered through MetricCollectors.
+func NewRegistry() *Registry {
+	return newRegistry(metricCollector.NewMetricCollectorRegistry())
+}
+
+func newRegistry(metricCollectors *metricCollector.MetricCollectorRegistry) *Registry {
+	return &Registry{
+		circuitBreakersMutex: &sync.RWMutex{},
+		circuitBreakers:      make(map[string]*CircuitBreaker),
+		settingsMutex:        &sync.RWMutex{},
+		circuitSettings:      make(map[string]*Settings),
+		applySettingsMutex:   &sync.Mutex{},
+		metricCollectors:     metricCollectors,
+		interceptorsMutex:    &sync.RWMutex{},
+		commandInterceptors:  make(map[string]interceptors),
+		stateListenersMutex:  &sync.RWMutex{},
+		threadPoolsMutex:     &sync.Mutex{},
+		threadPools:          make(map[string]*threadPool),
+		retryBudgetsMutex:    &sync.Mutex{},
+		retryBudgets:         make(map[retryBudgetKey]*retryBudget),
+		keyedCircuitsMutex:   &sync.RWMutex{},
+		keyedCircuits:        make(map[string]*keyedCircuits),
+	}
+}
+
+// DefaultRegistry returns the Registry used by the package level functions.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// MetricCollectors returns the registry of MetricCollector initializers used for new circuits of this Registry.
+// The default Registry uses metricCollector.Registry.
+func (r *Registry) MetricCollectors() *metricCollector.MetricCollectorRegistry {
+	return r.metricCollectors
+}
@@ -0,0 +1,45 @@
+package hystrix
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRegistryIsolation(t *testing.T) {
+	Convey("given two registries with a command of the same name", t, func() {
+		first := NewRegistry()
+		second := NewRegistry()
+		first.ConfigureCommand("shared", CommandConfig{MaxConcurrentRequests: 3})
+
+		Convey("settings are not shared", func() {
+			So(first.getSettings("shared").MaxConcurrentRequests, ShouldEqual, 3)
+			So(second.getSettings("shared").MaxConcurrentRequests, ShouldEqual, DefaultMaxConcurrent)
+			So(GetCircuitSettings()["shared"], ShouldBeNil)
+		})
+
+		Convey("after a failure on the first registry", func() {
+			err := first.Do("shared", func() error {
+				return fmt.Errorf("fail")
+			}, nil)
+			So(err, ShouldNotBeNil)
+			time.Sleep(10 * time.Millisecond)
+
+			Convey("only its own circuit records the failure", func() {
+				cb1, _, _ := first.GetCircuit("shared")
+				cb2, created, _ := second.GetCircuit("shared")
+				So(created, ShouldBeTrue)
+				So(cb1.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 1)
+				So(cb2.metrics.DefaultCollector().Failures().Sum(time.Now()), ShouldEqual, 0)
+			})
+
+			Convey("flushing the default registry keeps its circuit", func() {
+				Flush()
+				_, created, _ := first.GetCircuit("shared")
+				So(created, ShouldBeFalse)
+			})
+		})
+	})
+}
@@ -0,0 +1,204 @@
+package hystrix
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/rolling"
+)
+
+// DefaultRetryMultiplier is the factor by which the backoff between retries grows when a RetryPolicy sets none
+const DefaultRetryMultiplier = 2
+
+// RetryPolicy calls the run function of a command again when it fails, within the same execution.
+// Retries share the execution slot and the Timeout of the command, failed attempts are reported to
+// the metric collectors as failures of their own, so that the error percent of the circuit reflects
+// every call made to the dependency. Retries stop as soon as the circuit is no longer closed, which
+// also means that the trials of a half-open circuit are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is how many times run is called at most, including the first call. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, each further wait is Multiplier times longer
+	// up to MaxBackoff, which is unbounded when zero.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomly shortens each wait by up to this fraction of it, between 0 and 1, so that callers
+	// which failed together do not retry together.
+	Jitter float64
+	// Retryable returns whether a failed attempt is worth retrying, every error is when it is nil.
+	// Errors of the context of the command are never retried.
+	Retryable func(error) bool
+	// Budget caps the retries of the circuit, or of its CommandGroup, there is no cap when it is nil.
+	Budget *RetryBudget
+}
+
+// RetryBudget caps retries at a percent of the requests which the circuit counted during its rolling window,
+// like a token bucket which every request fills and every retry drains. Commands which would retry beyond
+// it fail at once with an error wrapping both ErrRetryBudgetExhausted and the error of their failed attempt,
+// so that retries cannot multiply the load on a dependency which is failing for every caller.
+type RetryBudget struct {
+	// Percent of the requests which may be retried, e.g. 10 allows one retry for every 10 requests.
+	Percent int
+	// MinRetries are allowed during each window regardless of the number of requests, so that rarely used
+	// circuits can retry at all.
+	MinRetries int
+	// PerCommandGroup shares the budget between the circuits of the CommandGroup, counting the requests of
+	// all of them whether they retry or not, and the retries during the rolling window of the first of them
+	// to retry. Circuits without a group have a budget of their own.
+	PerCommandGroup bool
+}
+
+// backoff returns the wait before the given retry, the first retry being 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = DefaultRetryMultiplier
+	}
+
+	backoff := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff -= backoff * math.Min(p.Jitter, 1) * rand.Float64()
+	}
+	return time.Duration(backoff)
+}
+
+func (p *RetryPolicy) retryable(err error) bool {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return false
+	}
+	return p.Retryable == nil || p.Retryable(err)
+}
+
+// runWithRetries calls run until it succeeds or policy gives up, without waiting past deadline,
+// and returns the error of the last attempt. Retries are taken from budget unless it is nil.
+func (c *command) runWithRetries(ctx context.Context, run runFuncC, policy *RetryPolicy, budget *retryBudget, deadline time.Time) error {
+	for attempt := 1; ; attempt++ {
+		attemptStart := time.Now()
+		err := run(ctx)
+		if err == nil || attempt >= policy.MaxAttempts || ctx.Err() != nil || !policy.retryable(err) {
+			return err
+		}
+
+		backoff := policy.backoff(attempt)
+		if time.Now().Add(backoff).After(deadline) {
+			return err
+		}
+		if budget != nil && !budget.withdraw(policy.Budget) {
+			return fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+		}
+		c.reportAttempt(attemptStart, err)
+		if c.circuit.IsOpen() {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		if c.circuit.IsOpen() {
+			return err
+		}
+	}
+}
+
+// reportAttempt reports a failed attempt which is retried to the metric collectors, the outcome of
+// the last attempt is reported with the command.
+func (c *command) reportAttempt(start time.Time, err error) {
+	duration := time.Since(start)
+	reportErr := c.circuit.reportResult(&ExecutionResult{
+		Events:        []EventType{EventFailure},
+		Start:         start,
+		RunDuration:   duration,
+		TotalDuration: duration,
+		Err:           err,
+	})
+	if reportErr != nil {
+		log.Print(reportErr)
+	}
+}
+
+// retryBudget counts the retries of a circuit, or of a command group, over a rolling window.
+type retryBudget struct {
+	mutex   sync.Mutex
+	retries *rolling.Number
+	// requests returns the requests counted by the circuits sharing the budget during their rolling windows
+	requests func(now time.Time) float64
+}
+
+// retryBudgetKey identifies the budget of a circuit, or of a command group when group is set.
+type retryBudgetKey struct {
+	name  string
+	group bool
+}
+
+func newRetryBudget(requests func(now time.Time) float64, window time.Duration, buckets int) *retryBudget {
+	return &retryBudget{
+		retries:  rolling.NewNumberWithWindow(window, buckets),
+		requests: requests,
+	}
+}
+
+// withdraw records a retry and returns true when the budget allows it.
+func (b *retryBudget) withdraw(budget *RetryBudget) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	allowed := float64(budget.MinRetries) + b.requests(now)*float64(budget.Percent)/100
+	if b.retries.Sum(now)+1 > allowed {
+		return false
+	}
+	b.retries.Increment(1)
+	return true
+}
+
+// getRetryBudget returns the retry budget of the command described by settings, or nil when its
+// retry policy has none.
+func (r *Registry) getRetryBudget(settings *Settings) *retryBudget {
+	if settings.RetryPolicy == nil || settings.RetryPolicy.Budget == nil {
+		return nil
+	}
+
+	key := retryBudgetKey{name: settings.CommandName}
+	if settings.RetryPolicy.Budget.PerCommandGroup && settings.CommandGroup != "" {
+		key = retryBudgetKey{name: settings.CommandGroup, group: true}
+	}
+
+	r.retryBudgetsMutex.Lock()
+	defer r.retryBudgetsMutex.Unlock()
+
+	budget, ok := r.retryBudgets[key]
+	if !ok {
+		window, buckets := settings.rollingWindow()
+		budget = newRetryBudget(func(now time.Time) float64 {
+			return r.retryBudgetRequests(key, now)
+		}, window, buckets)
+		r.retryBudgets[key] = budget
+	}
+	return budget
+}
+
+// retryBudgetRequests returns the requests counted during their rolling windows by the circuits, keyed ones
+// included, which share the retry budget identified by key.
+func (r *Registry) retryBudgetRequests(key retryBudgetKey, now time.Time) float64 {
+	var requests float64
+	for _, cb := range r.allCircuits() {
+		if key.group && cb.CommandGroup == key.name || !key.group && cb.command == key.name {
+			requests += cb.metrics.Requests().Sum(now)
+		}
+	}
+	return requests
+}
@@ -0,0 +1,161 @@
+package hystrix
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	Convey("given a circuit retrying failed commands up to 3 attempts", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("retry", CommandConfig{Timeout: 200})
+		registry.UpdateSettings("retry", func(s *Settings) {
+			s.RetryPolicy = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+		})
+		cb, _, _ := registry.GetCircuit("retry")
+		log := &callLog{done: make(chan []EventType, 10)}
+		registry.AddInterceptor(&recordingInterceptor{name: "audit", log: log})
+
+		attempts := 0
+		failTimes := func(n int, err error) func() error {
+			return func() error {
+				attempts++
+				if attempts <= n {
+					return err
+				}
+				return nil
+			}
+		}
+		waitForRequests := func(n float64) {
+			for cb.Metrics().NumRequests().Sum(time.Now()) < n {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		Convey("a command which fails once succeeds on its second attempt", func() {
+			So(registry.Do("retry", failTimes(1, errors.New("flaky")), nil), ShouldBeNil)
+			So(attempts, ShouldEqual, 2)
+			So(<-log.done, ShouldResemble, []EventType{EventSuccess})
+
+			Convey("and every attempt is counted by the circuit", func() {
+				waitForRequests(2)
+				So(cb.Metrics().Failures().Sum(time.Now()), ShouldEqual, 1)
+				So(cb.Metrics().Successes().Sum(time.Now()), ShouldEqual, 1)
+			})
+		})
+
+		Convey("a command stops after MaxAttempts", func() {
+			err := registry.Do("retry", failTimes(5, errors.New("down")), nil)
+			So(err.Error(), ShouldEqual, "down")
+			So(attempts, ShouldEqual, 3)
+			waitForRequests(3)
+			So(cb.Metrics().Failures().Sum(time.Now()), ShouldEqual, 3)
+		})
+
+		Convey("errors which are not retryable are returned at once", func() {
+			notFound := errors.New("not found")
+			registry.UpdateSettings("retry", func(s *Settings) {
+				s.RetryPolicy = &RetryPolicy{MaxAttempts: 3, Retryable: func(err error) bool { return err != notFound }}
+			})
+			So(registry.Do("retry", failTimes(5, notFound), nil), ShouldEqual, notFound)
+			So(attempts, ShouldEqual, 1)
+		})
+
+		Convey("retries do not wait past the timeout of the command", func() {
+			registry.UpdateSettings("retry", func(s *Settings) {
+				s.RetryPolicy = &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second}
+			})
+			start := time.Now()
+			So(registry.Do("retry", failTimes(5, errors.New("down")), nil).Error(), ShouldEqual, "down")
+			So(attempts, ShouldEqual, 1)
+			So(time.Since(start), ShouldBeLessThan, 200*time.Millisecond)
+		})
+
+		Convey("retries stop once the circuit opens", func() {
+			err := registry.DoC(context.Background(), "retry", func(ctx context.Context) error {
+				attempts++
+				cb.ForceOpen()
+				return errors.New("down")
+			}, nil)
+			So(err.Error(), ShouldEqual, "down")
+			So(attempts, ShouldEqual, 1)
+		})
+	})
+}
+
+func TestRetryBackoff(t *testing.T) {
+	Convey("given a retry policy with exponential backoff", t, func() {
+		policy := &RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+		Convey("the wait doubles for each retry up to MaxBackoff", func() {
+			So(policy.backoff(1), ShouldEqual, 10*time.Millisecond)
+			So(policy.backoff(2), ShouldEqual, 20*time.Millisecond)
+			So(policy.backoff(3), ShouldEqual, 40*time.Millisecond)
+			So(policy.backoff(4), ShouldEqual, 50*time.Millisecond)
+		})
+
+		Convey("jitter shortens the wait by up to its fraction", func() {
+			policy.Jitter = 0.5
+			for i := 0; i < 100; i++ {
+				backoff := policy.backoff(2)
+				So(backoff, ShouldBeBetweenOrEqual, 10*time.Millisecond, 20*time.Millisecond)
+			}
+		})
+	})
+}
+
+func TestRetryBudget(t *testing.T) {
+	Convey("given two circuits of a group sharing a budget of one retry per four requests", t, func() {
+		registry := NewRegistry()
+		policy := &RetryPolicy{MaxAttempts: 2, Budget: &RetryBudget{Percent: 25, PerCommandGroup: true}}
+		for _, name := range []string{"search/users", "search/orders"} {
+			registry.ConfigureCommand(name, CommandConfig{CommandGroup: "search"})
+			registry.UpdateSettings(name, func(s *Settings) { s.RetryPolicy = policy })
+		}
+		registry.ConfigureCommand("search/index", CommandConfig{CommandGroup: "search"})
+		down := errors.New("down")
+		fail := func() error { return down }
+		waitForRequests := func(name string, n float64) {
+			cb, _, _ := registry.GetCircuit(name)
+			for cb.Metrics().NumRequests().Sum(time.Now()) < n {
+				time.Sleep(time.Millisecond)
+			}
+		}
+
+		Convey("the first failed command may not retry", func() {
+			err := registry.Do("search/users", fail, nil)
+			So(errors.Is(err, ErrRetryBudgetExhausted), ShouldBeTrue)
+			So(errors.Is(err, down), ShouldBeTrue)
+			waitForRequests("search/users", 1)
+
+			Convey("but once the group counted four requests, including those of commands without retries", func() {
+				for i := 0; i < 3; i++ {
+					So(registry.Do("search/index", func() error { return nil }, nil), ShouldBeNil)
+				}
+				waitForRequests("search/index", 3)
+
+				Convey("another command of the group may", func() {
+					So(registry.Do("search/orders", fail, nil), ShouldEqual, down)
+					waitForRequests("search/orders", 2)
+
+					Convey("after which the budget is used up again", func() {
+						So(errors.Is(registry.Do("search/users", fail, nil), ErrRetryBudgetExhausted), ShouldBeTrue)
+					})
+				})
+			})
+		})
+
+		Convey("circuits of other groups have a budget of their own", func() {
+			registry.ConfigureCommand("billing", CommandConfig{CommandGroup: "billing"})
+			registry.UpdateSettings("billing", func(s *Settings) {
+				s.RetryPolicy = &RetryPolicy{MaxAttempts: 2, Budget: &RetryBudget{MinRetries: 1, PerCommandGroup: true}}
+			})
+			So(errors.Is(registry.Do("search/users", fail, nil), ErrRetryBudgetExhausted), ShouldBeTrue)
+			So(registry.Do("billing", fail, nil), ShouldEqual, down)
+		})
+	})
+}
@@ -1,113 +1,142 @@
 package rolling
 
 import (
-	"sync"
+	"math"
+	"sync/atomic"
 	"time"
 )
 
 // Number tracks a numberBucket over a bounded number of
-// time buckets. Currently the buckets are one second long and only the last 10 seconds are kept.
+// time buckets. By default the buckets are one second long and only the last 10 seconds are kept.
+//
+// The buckets form a fixed ring which is updated atomically, so a Number can be shared
+// by many goroutines without any lock.
 type Number struct {
-	Buckets map[int64]*numberBucket
-	Mutex   *sync.RWMutex
+	buckets     []atomic.Pointer[numberBucket]
+	bucketWidth int64
+	numBuckets  int64
 }
 
+// numberBucket holds the value of a single time bucket, identified by key.
+// Buckets are replaced rather than reset when the ring wraps around.
 type numberBucket struct {
-	Value float64
+	key   int64
+	value atomic.Uint64 // bits of a float64
 }
 
-// NewNumber initializes a RollingNumber struct.
+// NewNumber initializes a RollingNumber struct keeping 10 buckets of one second.
 func NewNumber() *Number {
+	return NewNumberWithWindow(10*time.Second, 10)
+}
+
+// NewNumberWithWindow initializes a RollingNumber struct covering window, divided into the given number of buckets.
+// The window must be a multiple of the number of buckets.
+func NewNumberWithWindow(window time.Duration, buckets int) *Number {
 	r := &Number{
-		Buckets: make(map[int64]*numberBucket),
-		Mutex:   &sync.RWMutex{},
+		buckets:     make([]atomic.Pointer[numberBucket], buckets),
+		bucketWidth: bucketWidth(window, buckets),
+		numBuckets:  int64(buckets),
 	}
 	return r
 }
 
-func (r *Number) getCurrentBucket() *numberBucket {
-	now := time.Now().Unix()
-	var bucket *numberBucket
-	var ok bool
+// bucketWidth returns the length of a single bucket in nanoseconds.
+func bucketWidth(window time.Duration, buckets int) int64 {
+	if window <= 0 || buckets <= 0 {
+		panic("rolling: window and buckets must be positive")
+	}
 
-	if bucket, ok = r.Buckets[now]; !ok {
-		bucket = &numberBucket{}
-		r.Buckets[now] = bucket
+	width := window.Nanoseconds() / int64(buckets)
+	if width <= 0 {
+		panic("rolling: buckets must not be shorter than a nanosecond")
 	}
+	return width
+}
 
-	return bucket
+func (r *Number) bucketKey(t time.Time) int64 {
+	return t.UnixNano() / r.bucketWidth
 }
 
-func (r *Number) removeOldBuckets() {
-	now := time.Now().Unix() - 10
+// getCurrentBucket returns the bucket for the current time, replacing the expired
+// bucket occupying its slot in the ring when needed.
+func (r *Number) getCurrentBucket() *numberBucket {
+	key := r.bucketKey(time.Now())
+	slot := &r.buckets[key%r.numBuckets]
+
+	for {
+		bucket := slot.Load()
+		if bucket != nil && bucket.key >= key {
+			// a bucket newer than key only shows up when the clock goes backwards,
+			// count the value in it rather than losing it.
+			return bucket
+		}
 
-	for timestamp := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp <= now {
-			delete(r.Buckets, timestamp)
+		fresh := &numberBucket{key: key}
+		if slot.CompareAndSwap(bucket, fresh) {
+			return fresh
 		}
 	}
 }
 
 // Increment increments the number in current timeBucket.
 func (r *Number) Increment(i float64) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-
 	b := r.getCurrentBucket()
-	b.Value += i
-	r.removeOldBuckets()
+	for {
+		old := b.value.Load()
+		if b.value.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+i)) {
+			return
+		}
+	}
 }
 
 // UpdateMax updates the maximum value in the current bucket.
 func (r *Number) UpdateMax(n float64) {
-	r.Mutex.Lock()
-	defer r.Mutex.Unlock()
-
 	b := r.getCurrentBucket()
-	if n > b.Value {
-		b.Value = n
+	for {
+		old := b.value.Load()
+		if n <= math.Float64frombits(old) {
+			return
+		}
+		if b.value.CompareAndSwap(old, math.Float64bits(n)) {
+			return
+		}
 	}
-	r.removeOldBuckets()
 }
 
-// Sum sums the values over the buckets in the last 10 seconds.
-func (r *Number) Sum(now time.Time) float64 {
-	sum := float64(0)
-
-	r.Mutex.RLock()
-	defer r.Mutex.RUnlock()
-
-	for timestamp, bucket := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp >= now.Unix()-10 {
-			sum += bucket.Value
+// forEach calls fn with the value of each bucket in the rolling window ending at now.
+func (r *Number) forEach(now time.Time, fn func(float64)) {
+	oldest := r.bucketKey(now) - r.numBuckets
+	for i := range r.buckets {
+		bucket := r.buckets[i].Load()
+		if bucket != nil && bucket.key > oldest {
+			fn(math.Float64frombits(bucket.value.Load()))
 		}
 	}
+}
+
+// Sum sums the values over the buckets in the rolling window.
+func (r *Number) Sum(now time.Time) float64 {
+	sum := float64(0)
+	r.forEach(now, func(v float64) {
+		sum += v
+	})
 
 	return sum
 }
 
-// Max returns the maximum value seen in the last 10 seconds.
+// Max returns the maximum value seen in the rolling window.
 func (r *Number) Max(now time.Time) float64 {
 	var max float64
-
-	r.Mutex.RLock()
-	defer r.Mutex.RUnlock()
-
-	for timestamp, bucket := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp >= now.Unix()-10 {
-			if bucket.Value > max {
-				max = bucket.Value
-			}
+	r.forEach(now, func(v float64) {
+		if v > max {
+			max = v
 		}
-	}
+	})
 
 	return max
 }
 
-// Avg return the average value seen in the last 10 seconds.
+// Avg return the average value per bucket seen in the rolling window.
 func (r *Number) Avg(now time.Time) float64 {
-	return r.Sum(now) / 10
+	return r.Sum(now) / float64(r.numBuckets)
 }
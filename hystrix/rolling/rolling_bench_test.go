@@ -0,0 +1,122 @@
+package rolling
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// lockedNumber is the map and mutex based implementation Number used to have,
+// kept to compare the ring buffer against it.
+type lockedNumber struct {
+	buckets map[int64]*lockedNumberBucket
+	mutex   *sync.RWMutex
+
+	bucketWidth int64
+	numBuckets  int64
+}
+
+type lockedNumberBucket struct {
+	value float64
+}
+
+func newLockedNumber() *lockedNumber {
+	return &lockedNumber{
+		buckets:     make(map[int64]*lockedNumberBucket),
+		mutex:       &sync.RWMutex{},
+		bucketWidth: bucketWidth(10*time.Second, 10),
+		numBuckets:  10,
+	}
+}
+
+func (r *lockedNumber) bucketKey(t time.Time) int64 {
+	return t.UnixNano() / r.bucketWidth
+}
+
+func (r *lockedNumber) getCurrentBucket() *lockedNumberBucket {
+	now := r.bucketKey(time.Now())
+	bucket, ok := r.buckets[now]
+	if !ok {
+		bucket = &lockedNumberBucket{}
+		r.buckets[now] = bucket
+	}
+
+	return bucket
+}
+
+func (r *lockedNumber) removeOldBuckets() {
+	now := r.bucketKey(time.Now()) - r.numBuckets
+
+	for key := range r.buckets {
+		if key <= now {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+func (r *lockedNumber) Increment(i float64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	b := r.getCurrentBucket()
+	b.value += i
+	r.removeOldBuckets()
+}
+
+func (r *lockedNumber) Sum(now time.Time) float64 {
+	sum := float64(0)
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	oldest := r.bucketKey(now) - r.numBuckets
+	for key, bucket := range r.buckets {
+		if key > oldest {
+			sum += bucket.value
+		}
+	}
+
+	return sum
+}
+
+type number interface {
+	Increment(float64)
+	Sum(time.Time) float64
+}
+
+// Run with -cpu 1,4,16 to compare how both implementations behave under contention.
+func BenchmarkNumberIncrement(b *testing.B) {
+	b.Run("ring", func(b *testing.B) { benchmarkIncrement(b, NewNumber()) })
+	b.Run("locked", func(b *testing.B) { benchmarkIncrement(b, newLockedNumber()) })
+}
+
+func BenchmarkNumberIncrementAndSum(b *testing.B) {
+	b.Run("ring", func(b *testing.B) { benchmarkIncrementAndSum(b, NewNumber()) })
+	b.Run("locked", func(b *testing.B) { benchmarkIncrementAndSum(b, newLockedNumber()) })
+}
+
+func benchmarkIncrement(b *testing.B, n number) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			n.Increment(1)
+		}
+	})
+}
+
+// benchmarkIncrementAndSum mixes in a read every 100 increments, roughly the ratio
+// of a busy command being watched by a metrics stream.
+func benchmarkIncrementAndSum(b *testing.B, n number) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if i%100 == 0 {
+				n.Sum(time.Now())
+			} else {
+				n.Increment(1)
+			}
+			i++
+		}
+	})
+}
@@ -0,0 +1,301 @@
+package rolling
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// Durations are counted in microseconds in log-linear buckets: the first group holds
+// one bucket per microsecond below 32µs, every following group covers the next power of two
+// with 32 buckets, so a recorded value is off by at most 1/32 of itself. Values above
+// the last group, about 38 hours, are counted in its last bucket.
+const (
+	histogramSubBucketBits = 5
+	histogramSubBuckets    = 1 << histogramSubBucketBits
+	histogramGroups        = 33
+)
+
+// Histogram counts time Durations for each time bucket in log-linear buckets of microsecond resolution.
+// Unlike Timing, its memory is bounded whatever the throughput, and percentiles are computed
+// without sorting the durations. Percentiles are exact below 32µs and within 3% above,
+// the minimum, maximum and mean are exact.
+type Histogram struct {
+	buckets     []atomic.Pointer[histogramBucket]
+	bucketWidth int64
+	numBuckets  int64
+
+	cached atomic.Pointer[histogramCache]
+}
+
+// histogramGroup holds the counts of the buckets covering one power of two.
+type histogramGroup [histogramSubBuckets]atomic.Uint64
+
+type histogramBucket struct {
+	key   int64
+	count atomic.Int64
+	sum   atomic.Int64
+	min   atomic.Int64
+	max   atomic.Int64
+
+	// groups are only allocated once a value falls in them, as durations
+	// usually span few powers of two.
+	groups [histogramGroups]atomic.Pointer[histogramGroup]
+}
+
+type histogramCache struct {
+	snapshot *HistogramSnapshot
+	time     int64
+}
+
+// NewHistogram creates a Histogram keeping 60 buckets of one second.
+func NewHistogram() *Histogram {
+	return NewHistogramWithWindow(60*time.Second, 60)
+}
+
+// NewHistogramWithWindow creates a Histogram covering window, divided into the given number of buckets.
+// The window must be a multiple of the number of buckets.
+func NewHistogramWithWindow(window time.Duration, buckets int) *Histogram {
+	return &Histogram{
+		buckets:     make([]atomic.Pointer[histogramBucket], buckets),
+		bucketWidth: bucketWidth(window, buckets),
+		numBuckets:  int64(buckets),
+	}
+}
+
+func (r *Histogram) bucketKey(t time.Time) int64 {
+	return t.UnixNano() / r.bucketWidth
+}
+
+func (r *Histogram) getCurrentBucket() *histogramBucket {
+	key := r.bucketKey(time.Now())
+	slot := &r.buckets[key%r.numBuckets]
+
+	for {
+		bucket := slot.Load()
+		if bucket != nil && bucket.key >= key {
+			return bucket
+		}
+
+		fresh := &histogramBucket{key: key}
+		fresh.min.Store(math.MaxInt64)
+		if slot.CompareAndSwap(bucket, fresh) {
+			return fresh
+		}
+	}
+}
+
+// histogramIndex returns the group and the bucket within the group counting v microseconds.
+func histogramIndex(v int64) (int, int) {
+	if v < histogramSubBuckets {
+		return 0, int(v)
+	}
+
+	group := bits.Len64(uint64(v)) - histogramSubBucketBits
+	if group >= histogramGroups {
+		return histogramGroups - 1, histogramSubBuckets - 1
+	}
+	return group, int(v>>(group-1)) - histogramSubBuckets
+}
+
+// histogramValue returns the value in microseconds reported for a bucket, the middle of its range.
+func histogramValue(group int, sub int) int64 {
+	if group == 0 {
+		return int64(sub)
+	}
+
+	width := int64(1) << (group - 1)
+	return int64(histogramSubBuckets+sub)*width + width/2
+}
+
+// Add counts the time.Duration given in the current time bucket.
+func (r *Histogram) Add(duration time.Duration) {
+	v := duration.Microseconds()
+	if v < 0 {
+		v = 0
+	}
+
+	b := r.getCurrentBucket()
+	group, sub := histogramIndex(v)
+	counts := b.groups[group].Load()
+	if counts == nil {
+		b.groups[group].CompareAndSwap(nil, &histogramGroup{})
+		counts = b.groups[group].Load()
+	}
+
+	counts[sub].Add(1)
+	b.count.Add(1)
+	b.sum.Add(v)
+	for {
+		min := b.min.Load()
+		if v >= min || b.min.CompareAndSwap(min, v) {
+			break
+		}
+	}
+	for {
+		max := b.max.Load()
+		if v <= max || b.max.CompareAndSwap(max, v) {
+			break
+		}
+	}
+}
+
+// Snapshot returns the counts of the rolling window ending now.
+func (r *Histogram) Snapshot() *HistogramSnapshot {
+	oldest := r.bucketKey(time.Now()) - r.numBuckets
+
+	s := NewHistogramSnapshot()
+	for i := range r.buckets {
+		b := r.buckets[i].Load()
+		if b == nil || b.key <= oldest || b.count.Load() == 0 {
+			continue
+		}
+
+		for group := range b.groups {
+			counts := b.groups[group].Load()
+			if counts == nil {
+				continue
+			}
+			for sub := range counts {
+				s.counts[group*histogramSubBuckets+sub] += int64(counts[sub].Load())
+			}
+		}
+		s.count += b.count.Load()
+		s.sum += b.sum.Load()
+		if min := b.min.Load(); min < s.min {
+			s.min = min
+		}
+		if max := b.max.Load(); max > s.max {
+			s.max = max
+		}
+	}
+
+	return s
+}
+
+// snapshot returns a Snapshot which is recomputed at most once per second.
+func (r *Histogram) snapshot() *HistogramSnapshot {
+	now := time.Now().UnixNano()
+	if c := r.cached.Load(); c != nil && c.time+time.Second.Nanoseconds() > now {
+		// don't recalculate if current cache is still fresh
+		return c.snapshot
+	}
+
+	s := r.Snapshot()
+	r.cached.Store(&histogramCache{snapshot: s, time: now})
+	return s
+}
+
+// Percentile returns the given percentile of the rolling window in milliseconds.
+func (r *Histogram) Percentile(p float64) uint32 {
+	return uint32(r.PercentileDuration(p).Nanoseconds() / 1000000)
+}
+
+// PercentileDuration returns the given percentile of the rolling window.
+func (r *Histogram) PercentileDuration(p float64) time.Duration {
+	return r.snapshot().Percentile(p)
+}
+
+// Mean returns the average duration of the rolling window in milliseconds.
+func (r *Histogram) Mean() uint32 {
+	return uint32(r.MeanDuration().Nanoseconds() / 1000000)
+}
+
+// MeanDuration returns the average duration of the rolling window.
+func (r *Histogram) MeanDuration() time.Duration {
+	return r.snapshot().Mean()
+}
+
+// HistogramSnapshot holds the counts of a Histogram at one point in time.
+// Snapshots of several histograms, for instance of the same command on different hosts,
+// can be merged to compute their combined percentiles.
+type HistogramSnapshot struct {
+	counts []int64
+	count  int64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+// NewHistogramSnapshot creates an empty HistogramSnapshot.
+func NewHistogramSnapshot() *HistogramSnapshot {
+	return &HistogramSnapshot{
+		counts: make([]int64, histogramGroups*histogramSubBuckets),
+		min:    math.MaxInt64,
+	}
+}
+
+// Merge adds the counts of other to the snapshot.
+func (s *HistogramSnapshot) Merge(other *HistogramSnapshot) {
+	for i, c := range other.counts {
+		s.counts[i] += c
+	}
+	s.count += other.count
+	s.sum += other.sum
+	if other.min < s.min {
+		s.min = other.min
+	}
+	if other.max > s.max {
+		s.max = other.max
+	}
+}
+
+// Count returns the number of durations in the snapshot.
+func (s *HistogramSnapshot) Count() int64 {
+	return s.count
+}
+
+// Min returns the shortest duration in the snapshot.
+func (s *HistogramSnapshot) Min() time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	return time.Duration(s.min) * time.Microsecond
+}
+
+// Max returns the longest duration in the snapshot.
+func (s *HistogramSnapshot) Max() time.Duration {
+	return time.Duration(s.max) * time.Microsecond
+}
+
+// Mean returns the average duration in the snapshot.
+func (s *HistogramSnapshot) Mean() time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+	return time.Duration(s.sum/s.count) * time.Microsecond
+}
+
+// Percentile returns the given percentile of the snapshot, using the same ordinal as Timing.
+func (s *HistogramSnapshot) Percentile(p float64) time.Duration {
+	if s.count == 0 {
+		return 0
+	}
+
+	pos := ordinal(s.count, p)
+	if pos <= 1 {
+		return s.Min()
+	}
+	if pos >= s.count {
+		return s.Max()
+	}
+
+	var seen int64
+	for i, c := range s.counts {
+		seen += c
+		if seen >= pos {
+			v := histogramValue(i/histogramSubBuckets, i%histogramSubBuckets)
+			// the middle of a bucket may lie outside of the values actually seen
+			if v < s.min {
+				v = s.min
+			}
+			if v > s.max {
+				v = s.max
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+
+	return s.Max()
+}
@@ -0,0 +1,138 @@
+package rolling
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHistogram(t *testing.T) {
+	Convey("given a new histogram", t, func() {
+		r := NewHistogram()
+
+		Convey("Mean() and Percentile() should be 0", func() {
+			So(r.Mean(), ShouldEqual, 0)
+			So(r.Percentile(50), ShouldEqual, 0)
+		})
+
+		Convey("after adding 2 timings", func() {
+			r.Add(100 * time.Millisecond)
+			r.Add(200 * time.Millisecond)
+
+			Convey("the mean should be the average of the timings", func() {
+				So(r.Mean(), ShouldEqual, 150)
+			})
+		})
+
+		Convey("after adding many timings", func() {
+			durations := []int{1, 1004, 1004, 1004, 1004, 1004, 1004, 1004, 1004, 1004, 1005, 1005, 1005, 1005, 1005, 1005, 1005, 1005, 1005, 1005, 1005, 1005, 1005, 1005, 1006, 1006, 1006, 1006, 1007, 1007, 1007, 1008, 1015}
+			for _, d := range durations {
+				r.Add(time.Duration(d) * time.Millisecond)
+			}
+
+			Convey("the minimum and maximum are exact", func() {
+				So(r.Percentile(0), ShouldEqual, 1)
+				So(r.Percentile(100), ShouldEqual, 1015)
+			})
+
+			Convey("percentiles are within the precision of the buckets", func() {
+				So(r.PercentileDuration(75), ShouldAlmostEqual, 1006*time.Millisecond, 1006*time.Millisecond/32)
+				So(r.PercentileDuration(99), ShouldAlmostEqual, 1015*time.Millisecond, 1015*time.Millisecond/32)
+			})
+		})
+
+		Convey("after adding sub-millisecond timings", func() {
+			r.Add(10 * time.Microsecond)
+			r.Add(20 * time.Microsecond)
+			r.Add(30 * time.Microsecond)
+
+			Convey("they are kept with microsecond precision", func() {
+				So(r.PercentileDuration(50), ShouldEqual, 20*time.Microsecond)
+				So(r.MeanDuration(), ShouldEqual, 20*time.Microsecond)
+			})
+		})
+	})
+}
+
+func TestHistogramIndex(t *testing.T) {
+	Convey("given values across several powers of two", t, func() {
+		Convey("each value is reported within 1/32 of itself", func() {
+			for _, v := range []int64{0, 1, 31, 32, 33, 63, 64, 65, 127, 1000, 123456, 1 << 36} {
+				group, sub := histogramIndex(v)
+				So(histogramValue(group, sub), ShouldAlmostEqual, v, v/32)
+			}
+		})
+
+		Convey("buckets are ordered", func() {
+			previous := int64(-1)
+			for v := int64(0); v < 1<<16; v++ {
+				group, sub := histogramIndex(v)
+				index := int64(group*histogramSubBuckets + sub)
+				So(index >= previous, ShouldBeTrue)
+				previous = index
+			}
+		})
+	})
+}
+
+func TestHistogramWithWindow(t *testing.T) {
+	Convey("given a histogram with a window of 2 buckets of 100ms", t, func() {
+		r := NewHistogramWithWindow(200*time.Millisecond, 2)
+		r.Add(100 * time.Millisecond)
+
+		Convey("the duration is kept within the window", func() {
+			So(r.Snapshot().Count(), ShouldEqual, 1)
+		})
+
+		Convey("the duration is dropped after the window", func() {
+			time.Sleep(300 * time.Millisecond)
+			r.Add(10 * time.Millisecond)
+
+			s := r.Snapshot()
+			So(s.Count(), ShouldEqual, 1)
+			So(s.Mean(), ShouldEqual, 10*time.Millisecond)
+		})
+	})
+}
+
+func TestHistogramSnapshotMerge(t *testing.T) {
+	Convey("given the snapshots of two histograms", t, func() {
+		a := NewHistogram()
+		b := NewHistogram()
+		for i := 1; i <= 50; i++ {
+			a.Add(time.Duration(i) * time.Microsecond)
+			b.Add(time.Duration(50+i) * time.Microsecond)
+		}
+
+		s := a.Snapshot()
+		s.Merge(b.Snapshot())
+
+		Convey("the merged snapshot summarizes both", func() {
+			So(s.Count(), ShouldEqual, 100)
+			So(s.Min(), ShouldEqual, time.Microsecond)
+			So(s.Max(), ShouldEqual, 100*time.Microsecond)
+			So(s.Percentile(50), ShouldAlmostEqual, 50*time.Microsecond, 2*time.Microsecond)
+		})
+	})
+}
+
+func BenchmarkHistogramAdd(b *testing.B) {
+	r := NewHistogram()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.Add(5 * time.Millisecond)
+		}
+	})
+}
+
+func BenchmarkTimingAdd(b *testing.B) {
+	r := NewTiming()
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			r.Add(5 * time.Millisecond)
+		}
+	})
+}
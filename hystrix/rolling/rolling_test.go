@@ -55,3 +55,23 @@ func BenchmarkRollingNumberUpdateMax(b *testing.B) {
 		n.UpdateMax(float64(i))
 	}
 }
+
+func TestNumberWithWindow(t *testing.T) {
+	Convey("when adding values to a rolling number with a window of 4 buckets of 50ms", t, func() {
+		n := NewNumberWithWindow(200*time.Millisecond, 4)
+		for _, x := range []float64{1, 2, 3} {
+			n.Increment(x)
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		Convey("all values are in the window", func() {
+			So(n.Sum(time.Now()), ShouldEqual, 6)
+			So(n.Avg(time.Now()), ShouldEqual, 1.5)
+		})
+
+		Convey("values older than the window are dropped", func() {
+			time.Sleep(250 * time.Millisecond)
+			So(n.Sum(time.Now()), ShouldEqual, 0)
+		})
+	})
+}
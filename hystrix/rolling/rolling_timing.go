@@ -7,30 +7,64 @@ import (
 	"time"
 )
 
+// Distribution records durations over a rolling window and summarizes them.
+// It is implemented by Timing, which keeps every duration, and Histogram,
+// which counts them in buckets of bounded size.
+type Distribution interface {
+	// Add records a duration in the current time bucket.
+	Add(time.Duration)
+	// Percentile returns the given percentile of the window in milliseconds.
+	Percentile(p float64) uint32
+	// PercentileDuration returns the given percentile of the window.
+	PercentileDuration(p float64) time.Duration
+	// Mean returns the average duration of the window in milliseconds.
+	Mean() uint32
+	// MeanDuration returns the average duration of the window.
+	MeanDuration() time.Duration
+}
+
 // Timing maintains time Durations for each time bucket.
 // The Durations are kept in an array to allow for a variety of
 // statistics to be calculated from the source data.
+//
+// Memory and the cost of computing percentiles grow with the number of durations
+// in the window, see Histogram for a bounded alternative.
 type Timing struct {
 	Buckets map[int64]*timingBucket
 	Mutex   *sync.RWMutex
 
 	CachedSortedDurations []time.Duration
 	LastCachedTime        int64
+
+	bucketWidth int64
+	numBuckets  int64
 }
 
 type timingBucket struct {
 	Durations []time.Duration
 }
 
-// NewTiming creates a RollingTiming struct.
+// NewTiming creates a RollingTiming struct keeping 60 buckets of one second.
 func NewTiming() *Timing {
+	return NewTimingWithWindow(60*time.Second, 60)
+}
+
+// NewTimingWithWindow creates a RollingTiming struct covering window, divided into the given number of buckets.
+// The window must be a multiple of the number of buckets.
+func NewTimingWithWindow(window time.Duration, buckets int) *Timing {
 	r := &Timing{
-		Buckets: make(map[int64]*timingBucket),
-		Mutex:   &sync.RWMutex{},
+		Buckets:     make(map[int64]*timingBucket),
+		Mutex:       &sync.RWMutex{},
+		bucketWidth: bucketWidth(window, buckets),
+		numBuckets:  int64(buckets),
 	}
 	return r
 }
 
+func (r *Timing) bucketKey(t time.Time) int64 {
+	return t.UnixNano() / r.bucketWidth
+}
+
 type byDuration []time.Duration
 
 func (c byDuration) Len() int           { return len(c) }
@@ -38,7 +72,7 @@ func (c byDuration) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
 func (c byDuration) Less(i, j int) bool { return c[i] < c[j] }
 
 // SortedDurations returns an array of time.Duration sorted from shortest
-// to longest that have occurred in the last 60 seconds.
+// to longest that have occurred in the rolling window.
 func (r *Timing) SortedDurations() []time.Duration {
 	r.Mutex.RLock()
 	t := r.LastCachedTime
@@ -55,9 +89,9 @@ func (r *Timing) SortedDurations() []time.Duration {
 	r.Mutex.Lock()
 	defer r.Mutex.Unlock()
 
-	for timestamp, b := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp >= now.Unix()-60 {
+	oldest := r.bucketKey(now) - r.numBuckets
+	for key, b := range r.Buckets {
+		if key > oldest {
 			for _, d := range b.Durations {
 				durations = append(durations, d)
 			}
@@ -74,28 +108,27 @@ func (r *Timing) SortedDurations() []time.Duration {
 
 func (r *Timing) getCurrentBucket() *timingBucket {
 	r.Mutex.RLock()
-	now := time.Now()
-	bucket, exists := r.Buckets[now.Unix()]
+	now := r.bucketKey(time.Now())
+	bucket, exists := r.Buckets[now]
 	r.Mutex.RUnlock()
 
 	if !exists {
 		r.Mutex.Lock()
 		defer r.Mutex.Unlock()
 
-		r.Buckets[now.Unix()] = &timingBucket{}
-		bucket = r.Buckets[now.Unix()]
+		r.Buckets[now] = &timingBucket{}
+		bucket = r.Buckets[now]
 	}
 
 	return bucket
 }
 
 func (r *Timing) removeOldBuckets() {
-	now := time.Now()
+	now := r.bucketKey(time.Now()) - r.numBuckets
 
-	for timestamp := range r.Buckets {
-		// TODO: configurable rolling window
-		if timestamp <= now.Unix()-60 {
-			delete(r.Buckets, timestamp)
+	for key := range r.Buckets {
+		if key <= now {
+			delete(r.Buckets, key)
 		}
 	}
 }
@@ -111,8 +144,13 @@ func (r *Timing) Add(duration time.Duration) {
 	r.removeOldBuckets()
 }
 
-// Percentile computes the percentile given with a linear interpolation.
+// Percentile computes the percentile given with a linear interpolation, in milliseconds.
 func (r *Timing) Percentile(p float64) uint32 {
+	return uint32(r.PercentileDuration(p).Nanoseconds() / 1000000)
+}
+
+// PercentileDuration computes the percentile given with a linear interpolation.
+func (r *Timing) PercentileDuration(p float64) time.Duration {
 	sortedDurations := r.SortedDurations()
 	length := len(sortedDurations)
 	if length <= 0 {
@@ -120,10 +158,14 @@ func (r *Timing) Percentile(p float64) uint32 {
 	}
 
 	pos := r.ordinal(len(sortedDurations), p) - 1
-	return uint32(sortedDurations[pos].Nanoseconds() / 1000000)
+	return sortedDurations[pos]
 }
 
 func (r *Timing) ordinal(length int, percentile float64) int64 {
+	return ordinal(int64(length), percentile)
+}
+
+func ordinal(length int64, percentile float64) int64 {
 	if percentile == 0 && length > 0 {
 		return 1
 	}
@@ -131,8 +173,13 @@ func (r *Timing) ordinal(length int, percentile float64) int64 {
 	return int64(math.Ceil((percentile / float64(100)) * float64(length)))
 }
 
-// Mean computes the average timing in the last 60 seconds.
+// Mean computes the average timing in the rolling window, in milliseconds.
 func (r *Timing) Mean() uint32 {
+	return uint32(r.MeanDuration().Nanoseconds() / 1000000)
+}
+
+// MeanDuration computes the average timing in the rolling window.
+func (r *Timing) MeanDuration() time.Duration {
 	sortedDurations := r.SortedDurations()
 	var sum time.Duration
 	for _, d := range sortedDurations {
@@ -144,5 +191,5 @@ func (r *Timing) Mean() uint32 {
 		return 0
 	}
 
-	return uint32(sum.Nanoseconds()/length) / 1000000
+	return time.Duration(sum.Nanoseconds() / length)
 }
@@ -68,3 +68,20 @@ func TestOrdinal(t *testing.T) {
 		})
 	})
 }
+
+func TestTimingWithWindow(t *testing.T) {
+	Convey("given a rolling timing with a window of 2 buckets of 100ms", t, func() {
+		r := NewTimingWithWindow(200*time.Millisecond, 2)
+		r.Add(100 * time.Millisecond)
+
+		Convey("the duration is kept within the window", func() {
+			So(r.Mean(), ShouldEqual, 100)
+		})
+
+		Convey("the duration is dropped after the window", func() {
+			time.Sleep(300 * time.Millisecond)
+			r.Add(10 * time.Millisecond)
+			So(r.Mean(), ShouldEqual, 10)
+		})
+	})
+}
@@ -1,7 +1,7 @@
 package hystrix
 
 import (
-	"sync"
+	"log"
 	"time"
 )
 
@@ -18,6 +18,24 @@ var (
 	DefaultErrorPercentThreshold = 50
 	// DefaultQueueSizeRejectionThreshold reject requests when the queue size exceeds the given limit
 	DefaultQueueSizeRejectionThreshold = DefaultMaxConcurrent * 5
+	// DefaultMetricsRollingWindow is how long, in milliseconds, the rolling counts used for health checks and metrics cover
+	DefaultMetricsRollingWindow = 10000
+	// DefaultMetricsRollingBuckets is the number of buckets the rolling count window is divided into
+	DefaultMetricsRollingBuckets = 10
+	// DefaultMetricsRollingPercentileWindow is how long, in milliseconds, durations are kept to compute latency percentiles
+	DefaultMetricsRollingPercentileWindow = 60000
+	// DefaultMetricsRollingPercentileBuckets is the number of buckets the latency percentile window is divided into
+	DefaultMetricsRollingPercentileBuckets = 60
+	// DefaultHalfOpenMaxRequests is how many trial requests a half-open circuit lets through
+	DefaultHalfOpenMaxRequests = 1
+	// DefaultHalfOpenSuccessPercent is the percent of the trial requests which must succeed to close a half-open circuit
+	DefaultHalfOpenSuccessPercent = 100
+	// DefaultFallbackMaxConcurrent is how many fallbacks of the same command can run at the same time
+	DefaultFallbackMaxConcurrent = 10
+	// DefaultMaxKeys is how many keyed circuits of the same command are kept, the least recently used is evicted beyond it
+	DefaultMaxKeys = 1000
+	// DefaultKeyIdleTimeout is how long, in milliseconds, a keyed circuit is kept without any command
+	DefaultKeyIdleTimeout = 600000
 )
 
 // Settings Setting for the hystrixCommand
@@ -30,6 +48,42 @@ type Settings struct {
 	SleepWindow                 time.Duration
 	ErrorPercentThreshold       int
 	QueueSizeRejectionThreshold int
+	// the window must be a multiple of the number of buckets, changing them resets the metrics of the circuit
+	MetricsRollingWindow            time.Duration
+	MetricsRollingBuckets           int
+	MetricsRollingPercentileWindow  time.Duration
+	MetricsRollingPercentileBuckets int
+	// the number of trial requests let through once the sleep window has passed, and the percent of them which
+	// must succeed to close the circuit, it opens again as soon as too many failed, or when some of them did not
+	// report within the longest of Timeout and SleepWindow
+	HalfOpenMaxRequests    int
+	HalfOpenSuccessPercent int
+	// ForceOpen short-circuits every command, ForceClosed runs every command however unhealthy the circuit is,
+	// ForceOpen wins when both are set
+	ForceOpen   bool
+	ForceClosed bool
+	// IsolationStrategy selects whether commands run on their own goroutine or on the caller's
+	IsolationStrategy IsolationStrategy
+	// ThreadPoolKey names the executor pool of the command, commands with the same key share their execution
+	// slots and queue but keep separate circuits. It defaults to the command name and is read when the circuit
+	// is created, the pool is sized by the settings of the first command using it and follows later changes
+	// of MaxConcurrentRequests and QueueSizeRejectionThreshold of any of its commands.
+	ThreadPoolKey string
+	// FallbackMaxConcurrentRequests is how many fallbacks of the command can run at the same time, commands
+	// beyond it fail with ErrFallbackRejected instead of calling their fallback
+	FallbackMaxConcurrentRequests int
+	// RetryPolicy retries failed commands within their execution, they are not retried when it is nil
+	RetryPolicy *RetryPolicy
+	// HedgePolicy starts a second attempt of slow commands, they are not hedged when it is nil
+	HedgePolicy *HedgePolicy
+	// MaxKeys is how many keyed circuits of the command are kept, the least recently used one is evicted to make
+	// room for a new key beyond it, and KeyIdleTimeout is how long one is kept without any command
+	MaxKeys        int
+	KeyIdleTimeout time.Duration
+	// AdaptiveLimit adjusts the number of commands the executor pool runs at the same time, up to
+	// MaxConcurrentRequests, from their latency and failures, the limit is static when it is nil. A pool shared
+	// through ThreadPoolKey follows the AdaptiveLimit of the command which created it and ignores the others
+	AdaptiveLimit *AdaptiveLimit
 }
 
 // CommandConfig is used to tune circuit settings at runtime
@@ -43,35 +97,144 @@ type CommandConfig struct {
 	ErrorPercentThreshold  int    `json:"error_percent_threshold"`
 	// for more details refer - https://github.com/Netflix/Hystrix/wiki/Configuration#maxqueuesize
 	QueueSizeRejectionThreshold int `json:"queue_size_rejection_threshold"`
+	// for more details refer - https://github.com/Netflix/Hystrix/wiki/Configuration#metrics
+	MetricsRollingWindow            int  `json:"metrics_rolling_window"`
+	MetricsRollingBuckets           int  `json:"metrics_rolling_buckets"`
+	MetricsRollingPercentileWindow  int  `json:"metrics_rolling_percentile_window"`
+	MetricsRollingPercentileBuckets int  `json:"metrics_rolling_percentile_buckets"`
+	HalfOpenMaxRequests             int  `json:"half_open_max_requests"`
+	HalfOpenSuccessPercent          int  `json:"half_open_success_percent"`
+	ForceOpen                       bool `json:"force_open"`
+	ForceClosed                     bool `json:"force_closed"`
+	// "thread", "semaphore" or "worker-pool"
+	IsolationStrategy             string `json:"isolation_strategy"`
+	ThreadPoolKey                 string `json:"thread_pool_key"`
+	FallbackMaxConcurrentRequests int    `json:"fallback_max_concurrent_requests"`
+	MaxKeys                       int    `json:"max_keys"`
+	KeyIdleTimeout                int    `json:"key_idle_timeout"`
 }
 
-var circuitSettings map[string]*Settings
-var settingsMutex *sync.RWMutex
+// Initialize initialize the hystrix library with specified circuit.
+// When the circuit already exists the new settings are applied to it immediately.
+func Initialize(config *Settings) {
+	defaultRegistry.Initialize(config)
+}
+
+// Initialize sets up the specified circuit of this registry.
+// When the circuit already exists the new settings are applied to it immediately.
+// config is copied, later changes to it have no effect on the circuit.
+func (r *Registry) Initialize(config *Settings) {
+	// settings are shared with running commands, so keep our own copy
+	settings := *config
+	settings.clampPoolSizes()
 
-func init() {
-	circuitSettings = make(map[string]*Settings)
-	settingsMutex = &sync.RWMutex{}
+	r.applySettingsMutex.Lock()
+	r.storeSettings(&settings)
+	changes := r.applySettings(&settings)
+	r.applySettingsMutex.Unlock()
+
+	notifyStateChanges(changes)
 }
 
-// Initialize initialize the hystrix library with specified circuit
-func Initialize(config *Settings) {
-	settingsMutex.Lock()
-	defer settingsMutex.Unlock()
+// UpdateSettings changes the settings of a command by applying update to a copy of its current settings.
+// The changes take effect immediately, including the size of the executor pool of a running circuit.
+// MaxConcurrentRequests below 1 and a negative QueueSizeRejectionThreshold are raised to 1 and 0.
+// update must not call back into hystrix.
+func UpdateSettings(name string, update func(*Settings)) {
+	defaultRegistry.UpdateSettings(name, update)
+}
+
+// UpdateSettings changes the settings of a command of this registry, see UpdateSettings.
+func (r *Registry) UpdateSettings(name string, update func(*Settings)) {
+	// live circuits must end up with the settings which were stored last
+	r.applySettingsMutex.Lock()
+	r.settingsMutex.Lock()
+	current, exists := r.circuitSettings[name]
+	if !exists {
+		current = commandSettings(name, CommandConfig{})
+	}
+	// settings are shared with running commands, so never modify them in place
+	updated := *current
+	update(&updated)
+	updated.CommandName = name
+	updated.clampPoolSizes()
+	r.circuitSettings[name] = &updated
+	r.settingsMutex.Unlock()
+	changes := r.applySettings(&updated)
+	r.applySettingsMutex.Unlock()
+
+	notifyStateChanges(changes)
+}
 
-	circuitSettings[config.CommandName] = config
+func (r *Registry) storeSettings(config *Settings) {
+	r.settingsMutex.Lock()
+	defer r.settingsMutex.Unlock()
+
+	r.circuitSettings[config.CommandName] = config
+}
+
+// applySettings updates the live state of an existing circuit, settings which are only read when
+// a command runs need no special handling. It returns the state changes of the circuits, whose listeners
+// are notified by notifyStateChanges.
+func (r *Registry) applySettings(config *Settings) map[*CircuitBreaker]*StateChange {
+	r.circuitBreakersMutex.RLock()
+	cb, ok := r.circuitBreakers[config.CommandName]
+	r.circuitBreakersMutex.RUnlock()
+
+	// keyed circuits share the settings of their command
+	circuits := r.getKeyedCircuits(config.CommandName)
+	if ok {
+		circuits = append(circuits, cb)
+	}
+
+	changes := make(map[*CircuitBreaker]*StateChange)
+	for _, cb := range circuits {
+		cb.threadPool.resize(config)
+		cb.metrics.setRollingWindows(config)
+		cb.executorPool.Metrics.SetRollingWindow(config.rollingWindow())
+		if change := cb.applyForce(config.ForceOpen, config.ForceClosed); change != nil {
+			changes[cb] = change
+		}
+	}
+	return changes
+}
+
+// notifyStateChanges notifies the listeners of the changes returned by applySettings. It must not be called
+// while holding applySettingsMutex, listeners may update settings.
+func notifyStateChanges(changes map[*CircuitBreaker]*StateChange) {
+	for cb, change := range changes {
+		cb.notifyStateChange(change)
+	}
 }
 
 // Configure applies settings for a set of circuits
 // deprecated: Use command builder along with initialize
 func Configure(cmds map[string]CommandConfig) {
+	defaultRegistry.Configure(cmds)
+}
+
+// Configure applies settings for a set of circuits of this registry
+// deprecated: Use command builder along with initialize
+func (r *Registry) Configure(cmds map[string]CommandConfig) {
 	for k, v := range cmds {
-		ConfigureCommand(k, v)
+		r.ConfigureCommand(k, v)
 	}
 }
 
 // ConfigureCommand applies settings for a circuit
 // deprecated: Use command builder along with initialize
 func ConfigureCommand(name string, config CommandConfig) {
+	defaultRegistry.ConfigureCommand(name, config)
+}
+
+// ConfigureCommand applies settings for a circuit of this registry
+// deprecated: Use command builder along with initialize
+func (r *Registry) ConfigureCommand(name string, config CommandConfig) {
+	r.Initialize(commandSettings(name, config))
+}
+
+// commandSettings fills in defaults for the values missing from config
+func commandSettings(name string, config CommandConfig) *Settings {
 
 	timeout := DefaultTimeout
 	if config.Timeout != 0 {
@@ -103,12 +266,61 @@ func ConfigureCommand(name string, config CommandConfig) {
 		queueSizeRejectionThreshold = config.QueueSizeRejectionThreshold
 	}
 
+	rollingWindow := DefaultMetricsRollingWindow
+	rollingBuckets := DefaultMetricsRollingBuckets
+	if config.MetricsRollingWindow != 0 && config.MetricsRollingBuckets != 0 {
+		rollingWindow = config.MetricsRollingWindow
+		rollingBuckets = config.MetricsRollingBuckets
+	}
+
+	percentileWindow := DefaultMetricsRollingPercentileWindow
+	percentileBuckets := DefaultMetricsRollingPercentileBuckets
+	if config.MetricsRollingPercentileWindow != 0 && config.MetricsRollingPercentileBuckets != 0 {
+		percentileWindow = config.MetricsRollingPercentileWindow
+		percentileBuckets = config.MetricsRollingPercentileBuckets
+	}
+
+	halfOpenMaxRequests := DefaultHalfOpenMaxRequests
+	if config.HalfOpenMaxRequests != 0 {
+		halfOpenMaxRequests = config.HalfOpenMaxRequests
+	}
+
+	halfOpenSuccessPercent := DefaultHalfOpenSuccessPercent
+	if config.HalfOpenSuccessPercent != 0 {
+		halfOpenSuccessPercent = config.HalfOpenSuccessPercent
+	}
+
+	fallbackMax := DefaultFallbackMaxConcurrent
+	if config.FallbackMaxConcurrentRequests != 0 {
+		fallbackMax = config.FallbackMaxConcurrentRequests
+	}
+
+	maxKeys := DefaultMaxKeys
+	if config.MaxKeys != 0 {
+		maxKeys = config.MaxKeys
+	}
+
+	keyIdleTimeout := DefaultKeyIdleTimeout
+	if config.KeyIdleTimeout != 0 {
+		keyIdleTimeout = config.KeyIdleTimeout
+	}
+
+	isolationStrategy := IsolationThread
+	if config.IsolationStrategy != "" {
+		strategy, ok := ParseIsolationStrategy(config.IsolationStrategy)
+		if ok {
+			isolationStrategy = strategy
+		} else {
+			log.Printf("hystrix-go: unknown isolation strategy %q for %v, using %v", config.IsolationStrategy, name, isolationStrategy)
+		}
+	}
+
 	groupName := name
 	if config.CommandGroup != "" {
 		groupName = config.CommandGroup
 	}
 
-	Initialize(&Settings{
+	return &Settings{
 		CommandName:                 name,
 		Timeout:                     time.Duration(timeout) * time.Millisecond,
 		CommandGroup:                groupName,
@@ -117,17 +329,129 @@ func ConfigureCommand(name string, config CommandConfig) {
 		SleepWindow:                 time.Duration(sleep) * time.Millisecond,
 		ErrorPercentThreshold:       errorPercent,
 		QueueSizeRejectionThreshold: queueSizeRejectionThreshold,
-	})
+
+		MetricsRollingWindow:            time.Duration(rollingWindow) * time.Millisecond,
+		MetricsRollingBuckets:           rollingBuckets,
+		MetricsRollingPercentileWindow:  time.Duration(percentileWindow) * time.Millisecond,
+		MetricsRollingPercentileBuckets: percentileBuckets,
+
+		HalfOpenMaxRequests:    halfOpenMaxRequests,
+		HalfOpenSuccessPercent: halfOpenSuccessPercent,
+
+		ForceOpen:   config.ForceOpen,
+		ForceClosed: config.ForceClosed,
+
+		IsolationStrategy: isolationStrategy,
+
+		ThreadPoolKey: config.ThreadPoolKey,
+
+		FallbackMaxConcurrentRequests: fallbackMax,
+
+		MaxKeys:        maxKeys,
+		KeyIdleTimeout: time.Duration(keyIdleTimeout) * time.Millisecond,
+	}
+}
+
+// rollingWindow returns the window of the rolling counts, settings created without one use the default.
+func (s *Settings) rollingWindow() (time.Duration, int) {
+	if s.MetricsRollingWindow <= 0 || s.MetricsRollingBuckets <= 0 {
+		return time.Duration(DefaultMetricsRollingWindow) * time.Millisecond, DefaultMetricsRollingBuckets
+	}
+	return s.MetricsRollingWindow, s.MetricsRollingBuckets
+}
+
+// rollingPercentileWindow returns the window of the latency percentiles, settings created without one use the default.
+func (s *Settings) rollingPercentileWindow() (time.Duration, int) {
+	if s.MetricsRollingPercentileWindow <= 0 || s.MetricsRollingPercentileBuckets <= 0 {
+		return time.Duration(DefaultMetricsRollingPercentileWindow) * time.Millisecond, DefaultMetricsRollingPercentileBuckets
+	}
+	return s.MetricsRollingPercentileWindow, s.MetricsRollingPercentileBuckets
+}
+
+// halfOpenMaxRequests returns the number of trial requests of a half-open circuit, settings created without one use the default.
+func (s *Settings) halfOpenMaxRequests() int {
+	if s.HalfOpenMaxRequests <= 0 {
+		return DefaultHalfOpenMaxRequests
+	}
+	return s.HalfOpenMaxRequests
+}
+
+// halfOpenSuccessPercent returns the percent of trial requests which must succeed, settings created without one use the default.
+func (s *Settings) halfOpenSuccessPercent() int {
+	if s.HalfOpenSuccessPercent <= 0 || s.HalfOpenSuccessPercent > 100 {
+		return DefaultHalfOpenSuccessPercent
+	}
+	return s.HalfOpenSuccessPercent
+}
+
+// clampPoolSizes raises MaxConcurrentRequests to 1 and QueueSizeRejectionThreshold to 0 when they are lower,
+// executor pools cannot be sized otherwise.
+func (s *Settings) clampPoolSizes() {
+	if s.MaxConcurrentRequests < 1 {
+		log.Printf("hystrix-go: max concurrent requests of %v raised from %d to 1", s.CommandName, s.MaxConcurrentRequests)
+		s.MaxConcurrentRequests = 1
+	}
+	if s.QueueSizeRejectionThreshold < 0 {
+		log.Printf("hystrix-go: queue size rejection threshold of %v raised from %d to 0", s.CommandName, s.QueueSizeRejectionThreshold)
+		s.QueueSizeRejectionThreshold = 0
+	}
+}
+
+// halfOpenTimeout returns how long a half-open circuit waits for the outcome of its trials before it opens again,
+// the longest of Timeout and SleepWindow.
+func (s *Settings) halfOpenTimeout() time.Duration {
+	if s.Timeout > s.SleepWindow {
+		return s.Timeout
+	}
+	return s.SleepWindow
+}
+
+// fallbackMaxConcurrentRequests returns how many fallbacks can run at the same time, settings created without
+// a limit use the default.
+func (s *Settings) fallbackMaxConcurrentRequests() int {
+	if s.FallbackMaxConcurrentRequests <= 0 {
+		return DefaultFallbackMaxConcurrent
+	}
+	return s.FallbackMaxConcurrentRequests
+}
+
+// maxKeys returns how many keyed circuits of the command are kept, settings created without a limit use the default.
+func (s *Settings) maxKeys() int {
+	if s.MaxKeys <= 0 {
+		return DefaultMaxKeys
+	}
+	return s.MaxKeys
+}
+
+// keyIdleTimeout returns how long keyed circuits are kept without any command, settings created without one use the default.
+func (s *Settings) keyIdleTimeout() time.Duration {
+	if s.KeyIdleTimeout <= 0 {
+		return time.Duration(DefaultKeyIdleTimeout) * time.Millisecond
+	}
+	return s.KeyIdleTimeout
+}
+
+// threadPoolKey returns the key of the thread pool of the command, which defaults to the command name.
+func (s *Settings) threadPoolKey() string {
+	if s.ThreadPoolKey == "" {
+		return s.CommandName
+	}
+	return s.ThreadPoolKey
 }
 
 func getSettings(name string) *Settings {
-	settingsMutex.RLock()
-	s, exists := circuitSettings[name]
-	settingsMutex.RUnlock()
+	return defaultRegistry.getSettings(name)
+}
+
+func (r *Registry) getSettings(name string) *Settings {
+	r.settingsMutex.RLock()
+	s, exists := r.circuitSettings[name]
+	r.settingsMutex.RUnlock()
 
 	if !exists {
-		ConfigureCommand(name, CommandConfig{})
-		s = getSettings(name)
+		// the circuit may be under construction, so only store the defaults
+		r.storeSettings(commandSettings(name, CommandConfig{}))
+		s = r.getSettings(name)
 	}
 
 	return s
@@ -135,13 +459,18 @@ func getSettings(name string) *Settings {
 
 // GetCircuitSettings Returns a copy of the hystrix circuit map
 func GetCircuitSettings() map[string]*Settings {
+	return defaultRegistry.GetCircuitSettings()
+}
+
+// GetCircuitSettings Returns a copy of the circuit map of this registry
+func (r *Registry) GetCircuitSettings() map[string]*Settings {
 	copy := make(map[string]*Settings)
 
-	settingsMutex.RLock()
-	for key, val := range circuitSettings {
+	r.settingsMutex.RLock()
+	for key, val := range r.circuitSettings {
 		copy[key] = val
 	}
-	settingsMutex.RUnlock()
+	r.settingsMutex.RUnlock()
 
 	return copy
 }
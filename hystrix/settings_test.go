@@ -1,6 +1,7 @@
 package hystrix
 
 import (
+	"sync"
 	"testing"
 	"time"
 
@@ -67,3 +68,113 @@ func TestGetCircuitSettings(t *testing.T) {
 		})
 	})
 }
+
+func TestUpdateSettings(t *testing.T) {
+	Convey("given a running circuit with 10 concurrent requests", t, func() {
+		defer Flush()
+		ConfigureCommand("update", CommandConfig{MaxConcurrentRequests: 10})
+		cb, _, err := GetCircuit("update")
+		So(err, ShouldBeNil)
+		previous := getSettings("update")
+
+		Convey("when its concurrency is updated to 20", func() {
+			UpdateSettings("update", func(s *Settings) {
+				s.MaxConcurrentRequests = 20
+			})
+
+			Convey("the settings change without modifying the previous ones", func() {
+				So(getSettings("update").MaxConcurrentRequests, ShouldEqual, 20)
+				So(getSettings("update").Timeout, ShouldEqual, previous.Timeout)
+				So(previous.MaxConcurrentRequests, ShouldEqual, 10)
+			})
+
+			Convey("the executor pool is resized", func() {
+				max, _ := cb.executorPool.Size()
+				So(max, ShouldEqual, 20)
+				So(len(cb.executorPool.Tickets), ShouldEqual, 20)
+			})
+		})
+
+		Convey("when invalid pool sizes are set", func() {
+			UpdateSettings("update", func(s *Settings) {
+				s.MaxConcurrentRequests = -1
+				s.QueueSizeRejectionThreshold = -5
+			})
+
+			Convey("they are raised to the smallest valid ones", func() {
+				So(getSettings("update").MaxConcurrentRequests, ShouldEqual, 1)
+				So(getSettings("update").QueueSizeRejectionThreshold, ShouldEqual, 0)
+				max, queue := cb.executorPool.Size()
+				So(max, ShouldEqual, 1)
+				So(queue, ShouldEqual, 0)
+			})
+		})
+
+		Convey("when it is configured again", func() {
+			ConfigureCommand("update", CommandConfig{MaxConcurrentRequests: 5, QueueSizeRejectionThreshold: 1})
+
+			Convey("the executor pool is resized", func() {
+				max, queue := cb.executorPool.Size()
+				So(max, ShouldEqual, 5)
+				So(queue, ShouldEqual, 1)
+			})
+		})
+
+		Convey("when it is initialized with settings which are changed afterwards", func() {
+			settings := *previous
+			settings.MaxConcurrentRequests = 0
+			Initialize(&settings)
+			settings.MaxConcurrentRequests = 50
+
+			Convey("the stored settings keep their clamped values", func() {
+				So(getSettings("update"), ShouldNotEqual, &settings)
+				So(getSettings("update").MaxConcurrentRequests, ShouldEqual, 1)
+				So(settings.MaxConcurrentRequests, ShouldEqual, 50)
+			})
+		})
+
+		Convey("a state change listener may update settings", func() {
+			registry := NewRegistry()
+			registry.ConfigureCommand("update", CommandConfig{})
+			registry.GetCircuit("update")
+			registry.OnStateChange(func(change StateChange) {
+				if change.To == CircuitOpen {
+					registry.ClearForce(change.Name)
+				}
+			})
+
+			done := make(chan struct{})
+			go func() {
+				registry.ForceOpen("update")
+				close(done)
+			}()
+			finished := false
+			select {
+			case <-done:
+				finished = true
+			case <-time.After(time.Second):
+			}
+			So(finished, ShouldBeTrue)
+			So(registry.GetCircuitSettings()["update"].ForceOpen, ShouldBeFalse)
+		})
+
+		Convey("when it is updated concurrently", func() {
+			var wg sync.WaitGroup
+			for i := 1; i <= 20; i++ {
+				wg.Add(1)
+				go func(max int) {
+					defer wg.Done()
+					UpdateSettings("update", func(s *Settings) {
+						s.MaxConcurrentRequests = max
+					})
+				}(i)
+			}
+			wg.Wait()
+
+			Convey("the executor pool matches the stored settings", func() {
+				max, _ := cb.executorPool.Size()
+				So(max, ShouldEqual, getSettings("update").MaxConcurrentRequests)
+			})
+		})
+	})
+}
@@ -0,0 +1,112 @@
+package hystrix
+
+import (
+	"sync"
+	"time"
+)
+
+// StateChange describes a transition of a circuit from one state to another.
+type StateChange struct {
+	Name         string
+	CommandGroup string
+	From         CircuitState
+	To           CircuitState
+	// Forced is whether the change was caused by forcing the circuit open or closed, or by clearing it.
+	Forced bool
+	// ErrorPercent and RequestVolume are read from the rolling metrics of the circuit at the time of the change.
+	ErrorPercent  int
+	RequestVolume uint64
+	Time          time.Time
+}
+
+// OnStateChange adds a listener called whenever a circuit changes state, e.g. to page someone when it opens.
+func OnStateChange(listener func(StateChange)) {
+	defaultRegistry.OnStateChange(listener)
+}
+
+// OnStateChange adds a listener called whenever a circuit of this registry changes state.
+//
+// Listeners are called in the order they were added, once the circuit is in its new state. The changes
+// of one circuit are reported one at a time in the order they happened, from the goroutine which caused
+// the change or from one still reporting an earlier change. Changes of different circuits may be reported
+// concurrently. Listeners must not block.
+func (r *Registry) OnStateChange(listener func(StateChange)) {
+	r.stateListenersMutex.Lock()
+	defer r.stateListenersMutex.Unlock()
+
+	// the slice is never modified once in use, adding a listener replaces it
+	listeners := make([]func(StateChange), 0, len(r.stateListeners)+1)
+	r.stateListeners = append(append(listeners, r.stateListeners...), listener)
+}
+
+func (r *Registry) getStateListeners() []func(StateChange) {
+	r.stateListenersMutex.RLock()
+	defer r.stateListenersMutex.RUnlock()
+
+	return r.stateListeners
+}
+
+// stateChangeQueue holds the changes of a circuit which were not reported to the listeners yet.
+type stateChangeQueue struct {
+	mutex   sync.Mutex
+	changes []StateChange
+	// reporting is whether a goroutine is reporting the queued changes
+	reporting bool
+}
+
+// stateChange returns the change of a circuit which was in state from, or nil when the state commands
+// observe did not change, and queues it for the listeners. The caller must hold the mutex, so that
+// changes are queued in the order they happen.
+func (circuit *CircuitBreaker) stateChange(from CircuitState, forced bool) *StateChange {
+	to := circuit.effectiveState()
+	if from == to {
+		return nil
+	}
+
+	now := time.Now()
+	change := &StateChange{
+		Name:          circuit.Name,
+		CommandGroup:  circuit.CommandGroup,
+		From:          from,
+		To:            to,
+		Forced:        forced,
+		ErrorPercent:  circuit.metrics.ErrorPercent(now),
+		RequestVolume: uint64(circuit.metrics.Requests().Sum(now)),
+		Time:          now,
+	}
+
+	queue := &circuit.stateChanges
+	queue.mutex.Lock()
+	queue.changes = append(queue.changes, *change)
+	queue.mutex.Unlock()
+	return change
+}
+
+// notifyStateChange reports the queued changes of the circuit to the listeners of the registry, unless change
+// is nil or another goroutine is reporting them already, in which case that goroutine reports change too.
+// It must not be called while holding the mutex of the circuit, listeners may inspect it.
+func (circuit *CircuitBreaker) notifyStateChange(change *StateChange) {
+	if change == nil {
+		return
+	}
+
+	queue := &circuit.stateChanges
+	queue.mutex.Lock()
+	if queue.reporting {
+		queue.mutex.Unlock()
+		return
+	}
+	queue.reporting = true
+	for len(queue.changes) > 0 {
+		next := queue.changes[0]
+		queue.changes = queue.changes[1:]
+
+		queue.mutex.Unlock()
+		for _, listener := range circuit.registry.getStateListeners() {
+			listener(next)
+		}
+		queue.mutex.Lock()
+	}
+	queue.reporting = false
+	queue.mutex.Unlock()
+}
@@ -0,0 +1,109 @@
+package hystrix
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestOnStateChange(t *testing.T) {
+	Convey("given a registry with a state change listener", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("watched", CommandConfig{SleepWindow: 10, RequestVolumeThreshold: 1})
+		changes := make(chan StateChange, 10)
+		registry.OnStateChange(func(change StateChange) {
+			changes <- change
+		})
+
+		cb, _, _ := registry.GetCircuit("watched")
+
+		Convey("tripping the circuit reports the change with the metrics which opened it", func() {
+			err := registry.Do("watched", func() error { return ErrTimeout }, nil)
+			So(err, ShouldNotBeNil)
+			for cb.metrics.Requests().Sum(time.Now()) < 1 {
+				time.Sleep(time.Millisecond)
+			}
+			So(cb.IsOpen(), ShouldBeTrue)
+
+			change := <-changes
+			So(change.Name, ShouldEqual, "watched")
+			So(change.From, ShouldEqual, CircuitClosed)
+			So(change.To, ShouldEqual, CircuitOpen)
+			So(change.Forced, ShouldBeFalse)
+			So(change.ErrorPercent, ShouldEqual, 100)
+			So(change.RequestVolume, ShouldEqual, 1)
+
+			Convey("and a successful trial after the sleep window reports half-open then closed", func() {
+				time.Sleep(20 * time.Millisecond)
+				So(registry.Do("watched", func() error { return nil }, nil), ShouldBeNil)
+
+				change := <-changes
+				So(change.From, ShouldEqual, CircuitOpen)
+				So(change.To, ShouldEqual, CircuitHalfOpen)
+
+				change = <-changes
+				So(change.From, ShouldEqual, CircuitHalfOpen)
+				So(change.To, ShouldEqual, CircuitClosed)
+			})
+		})
+
+		Convey("forcing the circuit open reports a forced change", func() {
+			cb.ForceOpen()
+			change := <-changes
+			So(change.From, ShouldEqual, CircuitClosed)
+			So(change.To, ShouldEqual, CircuitOpen)
+			So(change.Forced, ShouldBeTrue)
+			So(cb.State(), ShouldEqual, CircuitOpen)
+
+			Convey("and clearing it reports the change back", func() {
+				cb.ClearForce()
+				change := <-changes
+				So(change.From, ShouldEqual, CircuitOpen)
+				So(change.To, ShouldEqual, CircuitClosed)
+				So(change.Forced, ShouldBeTrue)
+			})
+		})
+
+		Convey("opening an open circuit reports nothing", func() {
+			cb.setOpen()
+			<-changes
+			cb.setOpen()
+			cb.ForceOpen()
+			So(changes, ShouldBeEmpty)
+		})
+	})
+}
+
+func TestStateChangeOrder(t *testing.T) {
+	Convey("given a listener which is slow to report a circuit opening", t, func() {
+		registry := NewRegistry()
+		registry.ConfigureCommand("ordered", CommandConfig{})
+		cb, _, _ := registry.GetCircuit("ordered")
+		release := make(chan struct{})
+		changes := make(chan StateChange, 10)
+		registry.OnStateChange(func(change StateChange) {
+			if change.To == CircuitOpen {
+				<-release
+			}
+			changes <- change
+		})
+
+		Convey("a change made while the opening is reported is reported after it", func() {
+			opened := make(chan struct{})
+			go func() {
+				cb.ForceOpen()
+				close(opened)
+			}()
+			for cb.State() != CircuitOpen {
+				time.Sleep(time.Millisecond)
+			}
+			cb.ClearForce()
+			close(release)
+			<-opened
+
+			So((<-changes).To, ShouldEqual, CircuitOpen)
+			So((<-changes).To, ShouldEqual, CircuitClosed)
+		})
+	})
+}
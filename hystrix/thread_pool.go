@@ -0,0 +1,165 @@
+package hystrix
+
+import (
+	"sync"
+	"time"
+)
+
+// threadPool holds the execution slots shared by the circuits of the commands with the same ThreadPoolKey,
+// each of which keeps a circuit of its own.
+type threadPool struct {
+	Key      string
+	executor *bufferedExecutorPool
+	// command is the command which created the pool, whose AdaptiveLimit is the only one applied to it
+	command string
+
+	// limiter sizes the pools of commands with an AdaptiveLimit, it is nil for the others
+	limiterMutex sync.Mutex
+	limiter      *concurrencyLimiter
+
+	// workers is started by the first command using IsolationWorkerPool
+	workersMutex sync.Mutex
+	workers      *workerPool
+}
+
+// getThreadPool returns the thread pool for the given command, creating it sized by the settings of the command
+// when it is the first of its key.
+func (r *Registry) getThreadPool(name string) *threadPool {
+	key := r.getSettings(name).threadPoolKey()
+
+	r.threadPoolsMutex.Lock()
+	defer r.threadPoolsMutex.Unlock()
+
+	pool, ok := r.threadPools[key]
+	if !ok {
+		pool = newThreadPool(key, r.getSettings(name))
+		r.threadPools[key] = pool
+	}
+	return pool
+}
+
+// newThreadPool creates a thread pool with the given key, sized by settings.
+func newThreadPool(key string, settings *Settings) *threadPool {
+	p := &threadPool{
+		Key:      key,
+		executor: newBufferedExecutorPool(key, settings),
+		command:  settings.CommandName,
+	}
+	p.resize(settings)
+	return p
+}
+
+// resize applies the MaxConcurrentRequests, QueueSizeRejectionThreshold and AdaptiveLimit of settings to the
+// executor pool, and to the worker pool once started. The AdaptiveLimit of commands other than the one which
+// created the pool is ignored, so that commands sharing it cannot turn off each other's adaptive limit.
+func (p *threadPool) resize(settings *Settings) {
+	p.limiterMutex.Lock()
+	defer p.limiterMutex.Unlock()
+
+	switch {
+	case settings.CommandName != p.command:
+		if p.limiter != nil {
+			shared := *settings
+			shared.AdaptiveLimit = &p.limiter.config
+			p.limiter.configure(&shared)
+		}
+	case settings.AdaptiveLimit == nil:
+		p.limiter = nil
+	case p.limiter == nil:
+		p.limiter = newConcurrencyLimiter(settings)
+	default:
+		p.limiter.configure(settings)
+	}
+	p.setSize(p.limit(settings), settings.QueueSizeRejectionThreshold)
+}
+
+// adaptive returns whether the pool is sized by an adaptive limit.
+func (p *threadPool) adaptive() bool {
+	p.limiterMutex.Lock()
+	defer p.limiterMutex.Unlock()
+
+	return p.limiter != nil
+}
+
+// observe passes the result of a command which ran on the pool to its adaptive limit, if any, and resizes the
+// pools when the limit changed.
+func (p *threadPool) observe(rtt time.Duration, dropped bool) {
+	p.limiterMutex.Lock()
+	defer p.limiterMutex.Unlock()
+
+	if p.limiter == nil {
+		return
+	}
+	inflight := p.executor.ActiveCount()
+	if workers := p.startedWorkerPool(); workers != nil {
+		inflight += workers.ActiveCount()
+	}
+	if p.limiter.observe(rtt, inflight, dropped) {
+		_, queueSizeRejectionThreshold := p.executor.Size()
+		p.setSize(p.limiter.Limit(), queueSizeRejectionThreshold)
+	}
+}
+
+// limit returns the number of execution slots of the pool for settings, the caller must hold limiterMutex.
+func (p *threadPool) limit(settings *Settings) int {
+	if p.limiter != nil {
+		return p.limiter.Limit()
+	}
+	return settings.MaxConcurrentRequests
+}
+
+// setSize resizes the executor pool, and the worker pool once started, the caller must hold limiterMutex.
+func (p *threadPool) setSize(max int, queueSizeRejectionThreshold int) {
+	p.executor.Resize(max, queueSizeRejectionThreshold)
+	if workers := p.startedWorkerPool(); workers != nil {
+		workers.Resize(max, queueSizeRejectionThreshold)
+	}
+}
+
+// flushThreadPools stops the worker pools of the registry and forgets every thread pool.
+func (r *Registry) flushThreadPools() {
+	r.threadPoolsMutex.Lock()
+	defer r.threadPoolsMutex.Unlock()
+
+	for key, pool := range r.threadPools {
+		pool.executor.Metrics.Reset()
+		if workers := pool.startedWorkerPool(); workers != nil {
+			workers.Stop()
+		}
+		delete(r.threadPools, key)
+	}
+}
+
+// workerPool returns the worker pool of the thread pool, starting it with settings on first use.
+func (p *threadPool) workerPool(settings *Settings) *workerPool {
+	if workers := p.startedWorkerPool(); workers != nil {
+		return workers
+	}
+
+	// the adaptive limit may change while the workers start
+	p.limiterMutex.Lock()
+	defer p.limiterMutex.Unlock()
+	p.workersMutex.Lock()
+	defer p.workersMutex.Unlock()
+
+	if p.workers == nil {
+		p.workers = newWorkerPool(p.Key, p.limit(settings), settings.QueueSizeRejectionThreshold, p.executor.Metrics)
+	}
+	return p.workers
+}
+
+// startedWorkerPool returns the worker pool of the thread pool, or nil when no command has used it yet.
+func (p *threadPool) startedWorkerPool() *workerPool {
+	p.workersMutex.Lock()
+	defer p.workersMutex.Unlock()
+
+	return p.workers
+}
+
+// stop stops the worker pool and the metrics of the thread pool, which must no longer be used.
+func (p *threadPool) stop() {
+	if workers := p.startedWorkerPool(); workers != nil {
+		workers.Stop()
+	}
+	p.executor.Metrics.Stop()
+}
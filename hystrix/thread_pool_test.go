@@ -0,0 +1,76 @@
+package hystrix
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestThreadPoolKey(t *testing.T) {
+	Convey("given two commands sharing a thread pool with a single slot", t, func() {
+		registry := NewRegistry()
+		defer registry.Flush()
+		registry.ConfigureCommand("users/get", CommandConfig{MaxConcurrentRequests: 1, QueueSizeRejectionThreshold: 1, Timeout: 1000, ThreadPoolKey: "users"})
+		registry.ConfigureCommand("users/list", CommandConfig{MaxConcurrentRequests: 1, QueueSizeRejectionThreshold: 1, Timeout: 100, ThreadPoolKey: "users"})
+		get, _, _ := registry.GetCircuit("users/get")
+		list, _, _ := registry.GetCircuit("users/list")
+
+		Convey("their circuits are separate", func() {
+			So(get, ShouldNotEqual, list)
+			list.ForceOpen()
+			So(get.IsOpen(), ShouldBeFalse)
+		})
+
+		Convey("they share the executor pool named by the key", func() {
+			So(get.executorPool, ShouldEqual, list.executorPool)
+			So(get.executorPool.Name, ShouldEqual, "users")
+		})
+
+		Convey("a running command of one takes the slot of the other", func() {
+			block := make(chan struct{})
+			defer close(block)
+			registry.Go("users/get", func() error {
+				<-block
+				return nil
+			}, nil)
+			for list.ActiveCount() < 1 {
+				time.Sleep(time.Millisecond)
+			}
+
+			So(registry.Do("users/list", func() error { return nil }, nil), ShouldEqual, ErrMaxConcurrency)
+		})
+
+		Convey("other commands keep a pool of their own", func() {
+			other, _, _ := registry.GetCircuit("orders")
+			So(other.executorPool, ShouldNotEqual, get.executorPool)
+			So(other.executorPool.Name, ShouldEqual, "orders")
+		})
+
+		Convey("resizing the pool for one resizes it for both", func() {
+			registry.UpdateSettings("users/list", func(s *Settings) { s.MaxConcurrentRequests = 5 })
+			max, _ := get.executorPool.Size()
+			So(max, ShouldEqual, 5)
+		})
+
+		Convey("the adaptive limit is set by the command which created the pool", func() {
+			registry.UpdateSettings("users/get", func(s *Settings) {
+				s.MaxConcurrentRequests = 10
+				s.AdaptiveLimit = &AdaptiveLimit{InitialLimit: 4}
+			})
+			So(get.ConcurrencyLimit(), ShouldEqual, 4)
+
+			Convey("and other commands cannot turn it off", func() {
+				registry.UpdateSettings("users/list", func(s *Settings) { s.MaxConcurrentRequests = 10 })
+				So(get.threadPool.adaptive(), ShouldBeTrue)
+				So(list.ConcurrencyLimit(), ShouldEqual, 4)
+			})
+
+			Convey("and removing it restores a static limit", func() {
+				registry.UpdateSettings("users/get", func(s *Settings) { s.AdaptiveLimit = nil })
+				So(get.threadPool.adaptive(), ShouldBeFalse)
+				So(get.ConcurrencyLimit(), ShouldEqual, 10)
+			})
+		})
+	})
+}
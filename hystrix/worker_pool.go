@@ -0,0 +1,182 @@
+package hystrix
+
+import (
+	"sync"
+)
+
+// workerPool runs the commands of circuits using IsolationWorkerPool on a fixed number of long-lived
+// goroutines, which take them in order from a queue of bounded size.
+type workerPool struct {
+	Name    string
+	Metrics *bufferedPoolMetrics
+
+	mutex sync.Mutex
+	// available is signalled when a task is queued, and broadcast when the pool is resized or stopped
+	available *sync.Cond
+	queue     []*workerTask
+	// workers is the number of running goroutines, which exceeds max until surplus workers finish their task
+	workers      int
+	max          int
+	maxQueueSize int
+	active       int
+	stopped      bool
+
+	// tasks is the number of tasks ever accepted and not canceled, completed the number of tasks which have run
+	tasks     uint64
+	completed uint64
+}
+
+// workerTask is a task accepted by a worker pool, which can be canceled until a worker takes it.
+type workerTask struct {
+	run func()
+}
+
+func newWorkerPool(name string, max int, maxQueueSize int, metrics *bufferedPoolMetrics) *workerPool {
+	p := &workerPool{
+		Name:         name,
+		Metrics:      metrics,
+		maxQueueSize: maxQueueSize,
+	}
+	p.available = sync.NewCond(&p.mutex)
+	p.Resize(max, maxQueueSize)
+
+	return p
+}
+
+// Submit queues run for the next free worker and returns the accepted task, nil when it was rejected,
+// along with whether it has to wait for a worker. Tasks are rejected once maxQueueSize of them are waiting.
+func (p *workerPool) Submit(run func()) (task *workerTask, queued bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stopped {
+		return nil, false
+	}
+
+	// workers which are not running a task take the tasks at the front of the queue, the others wait
+	waiting := len(p.queue) - (p.max - p.active)
+	if waiting >= p.maxQueueSize {
+		return nil, false
+	}
+
+	return p.enqueue(run), waiting >= 0
+}
+
+// TrySubmit hands run to a free worker and returns whether one was free, it never queues the task.
+func (p *workerPool) TrySubmit(run func()) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.stopped || len(p.queue) >= p.max-p.active {
+		return false
+	}
+
+	p.enqueue(run)
+	return true
+}
+
+// enqueue adds run to the queue, the caller must hold the mutex.
+func (p *workerPool) enqueue(run func()) *workerTask {
+	task := &workerTask{run: run}
+	p.queue = append(p.queue, task)
+	p.tasks++
+	p.available.Signal()
+
+	return task
+}
+
+// Cancel removes task from the queue, freeing its room for another task, and returns whether it was still
+// queued. Tasks which a worker took already run to completion.
+func (p *workerPool) Cancel(task *workerTask) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for i, queued := range p.queue {
+		if queued == task {
+			copy(p.queue[i:], p.queue[i+1:])
+			p.queue[len(p.queue)-1] = nil
+			p.queue = p.queue[:len(p.queue)-1]
+			p.tasks--
+			return true
+		}
+	}
+	return false
+}
+
+// Resize changes the number of workers and the size of the queue. Surplus workers finish their task first.
+func (p *workerPool) Resize(max int, maxQueueSize int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.max = max
+	p.maxQueueSize = maxQueueSize
+	for ; p.workers < p.max; p.workers++ {
+		go p.work()
+	}
+	p.available.Broadcast()
+}
+
+// Stop ends the workers once they finish their task, queued tasks are never run.
+func (p *workerPool) Stop() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.stopped = true
+	p.queue = nil
+	p.available.Broadcast()
+}
+
+func (p *workerPool) work() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for {
+		for len(p.queue) == 0 && !p.stopped && p.workers <= p.max {
+			p.available.Wait()
+		}
+		if p.stopped || p.workers > p.max {
+			p.workers--
+			return
+		}
+
+		task := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.active++
+		p.mutex.Unlock()
+
+		task.run()
+		p.Metrics.update(bufferedPoolMetricsUpdate{
+			activeCount:  p.ActiveCount(),
+			waitingCount: p.WaitingCount(),
+		})
+
+		p.mutex.Lock()
+		p.active--
+		p.completed++
+	}
+}
+
+// ActiveCount returns the number of workers running a task.
+func (p *workerPool) ActiveCount() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.active
+}
+
+// WaitingCount returns the number of queued tasks.
+func (p *workerPool) WaitingCount() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.queue)
+}
+
+// TaskCounts returns the number of tasks ever accepted and not canceled, and the number of those which have completed.
+func (p *workerPool) TaskCounts() (tasks uint64, completed uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.tasks, p.completed
+}
@@ -0,0 +1,126 @@
+package hystrix
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestWorkerPool(t *testing.T) {
+	Convey("given a worker pool with one worker and room for two queued tasks", t, func() {
+		pool := newWorkerPool("workers", 1, 2, newBufferedPoolMetrics("workers", 10*time.Second, 10))
+		defer pool.Stop()
+
+		block := make(chan struct{})
+		var mutex sync.Mutex
+		var order []int
+		done := make(chan struct{}, 3)
+		task := func(i int) func() {
+			return func() {
+				<-block
+				mutex.Lock()
+				order = append(order, i)
+				mutex.Unlock()
+				done <- struct{}{}
+			}
+		}
+
+		submitted, queued := pool.Submit(task(1))
+		So(submitted, ShouldNotBeNil)
+		for pool.ActiveCount() < 1 {
+			time.Sleep(time.Millisecond)
+		}
+		So(queued, ShouldBeFalse)
+
+		Convey("tasks beyond the queue are rejected", func() {
+			submitted, queued = pool.Submit(task(2))
+			So(submitted, ShouldNotBeNil)
+			So(queued, ShouldBeTrue)
+			submitted, _ = pool.Submit(task(3))
+			So(submitted, ShouldNotBeNil)
+			So(pool.WaitingCount(), ShouldEqual, 2)
+
+			submitted, _ = pool.Submit(task(4))
+			So(submitted, ShouldBeNil)
+
+			Convey("and queued tasks run in order", func() {
+				close(block)
+				for i := 0; i < 3; i++ {
+					<-done
+				}
+				So(order, ShouldResemble, []int{1, 2, 3})
+
+				for {
+					if _, completed := pool.TaskCounts(); completed == 3 {
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+				tasks, _ := pool.TaskCounts()
+				So(tasks, ShouldEqual, 3)
+			})
+		})
+
+		Convey("a canceled task leaves the queue and never runs", func() {
+			canceled, _ := pool.Submit(task(2))
+			submitted, _ = pool.Submit(task(3))
+			So(pool.Cancel(canceled), ShouldBeTrue)
+			So(pool.WaitingCount(), ShouldEqual, 1)
+			submitted, _ = pool.Submit(task(4))
+			So(submitted, ShouldNotBeNil)
+
+			close(block)
+			for i := 0; i < 3; i++ {
+				<-done
+			}
+			So(order, ShouldResemble, []int{1, 3, 4})
+			So(pool.Cancel(submitted), ShouldBeFalse)
+
+			for {
+				if _, completed := pool.TaskCounts(); completed == 3 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			tasks, _ := pool.TaskCounts()
+			So(tasks, ShouldEqual, 3)
+		})
+
+		Convey("tasks which would be queued are not tried", func() {
+			So(pool.TrySubmit(task(2)), ShouldBeFalse)
+			So(pool.WaitingCount(), ShouldEqual, 0)
+
+			pool.Resize(2, 2)
+			So(pool.TrySubmit(task(2)), ShouldBeTrue)
+			close(block)
+			<-done
+			<-done
+		})
+
+		Convey("resizing the pool runs queued tasks on new workers", func() {
+			submitted, _ = pool.Submit(task(2))
+			So(submitted, ShouldNotBeNil)
+			pool.Resize(2, 2)
+			for pool.ActiveCount() < 2 {
+				time.Sleep(time.Millisecond)
+			}
+			So(pool.WaitingCount(), ShouldEqual, 0)
+			close(block)
+		})
+
+		Convey("a stopped pool rejects tasks and drops its queue", func() {
+			submitted, _ = pool.Submit(task(2))
+			So(submitted, ShouldNotBeNil)
+			pool.Stop()
+			So(pool.WaitingCount(), ShouldEqual, 0)
+			submitted, _ = pool.Submit(task(3))
+			So(submitted, ShouldBeNil)
+
+			close(block)
+			<-done
+			So(order, ShouldResemble, []int{1})
+		})
+	})
+}
@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"math/rand"
@@ -75,23 +76,19 @@ func timedHandler(fn func(w http.ResponseWriter, r *http.Request), stats statsd.
 }
 
 func handle(w http.ResponseWriter, r *http.Request) {
-	done := make(chan struct{}, 1)
-	errChan := hystrix.Go("test", func() error {
+	body, err := hystrix.Execute(r.Context(), "test", func(ctx context.Context) (string, error) {
 		delta := rand.Intn(deltaWindow)
 		time.Sleep(time.Duration(delay+delta) * time.Millisecond)
-		done <- struct{}{}
-		return nil
-	}, func(err error) error {
-		done <- struct{}{}
-		return nil
+		return "OK", nil
+	}, func(ctx context.Context, err error) (string, error) {
+		return "OK", nil
 	})
-
-	select {
-	case err := <-errChan:
+	if err != nil {
 		http.Error(w, err.Error(), 500)
-	case <-done:
-		w.Write([]byte("OK"))
+		return
 	}
+
+	w.Write([]byte(body))
 }
 
 func rotateDelay() {
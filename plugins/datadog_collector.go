@@ -15,19 +15,23 @@ import (
 // own implemenation of DatadogClient
 const (
 	// DM = Datadog Metric
-	dmCircuitOpen       = "hystrix.circuitOpen"
-	dmAttempts          = "hystrix.attempts"
-	dmQueueLength       = "hystrix.queueLength"
-	dmErrors            = "hystrix.errors"
-	dmSuccesses         = "hystrix.successes"
-	dmFailures          = "hystrix.failures"
-	dmRejects           = "hystrix.rejects"
-	dmShortCircuits     = "hystrix.shortCircuits"
-	dmTimeouts          = "hystrix.timeouts"
-	dmFallbackSuccesses = "hystrix.fallbackSuccesses"
-	dmFallbackFailures  = "hystrix.fallbackFailures"
-	dmTotalDuration     = "hystrix.totalDuration"
-	dmRunDuration       = "hystrix.runDuration"
+	dmCircuitOpen        = "hystrix.circuitOpen"
+	dmAttempts           = "hystrix.attempts"
+	dmQueueLength        = "hystrix.queueLength"
+	dmErrors             = "hystrix.errors"
+	dmSuccesses          = "hystrix.successes"
+	dmFailures           = "hystrix.failures"
+	dmRejects            = "hystrix.rejects"
+	dmShortCircuits      = "hystrix.shortCircuits"
+	dmTimeouts           = "hystrix.timeouts"
+	dmContextCanceled    = "hystrix.contextCanceled"
+	dmContextDeadline    = "hystrix.contextDeadlineExceeded"
+	dmFallbackSuccesses  = "hystrix.fallbackSuccesses"
+	dmFallbackFailures   = "hystrix.fallbackFailures"
+	dmFallbackRejections = "hystrix.fallbackRejections"
+	dmHedges             = "hystrix.hedges"
+	dmTotalDuration      = "hystrix.totalDuration"
+	dmRunDuration        = "hystrix.runDuration"
 )
 
 type (
@@ -167,6 +171,18 @@ func (dc *DatadogCollector) IncrementTimeouts() {
 	_ = dc.client.Count(dmTimeouts, 1, dc.tags, 1.0)
 }
 
+// IncrementContextCanceled increments the number of requests abandoned because
+// the caller's context was canceled.
+func (dc *DatadogCollector) IncrementContextCanceled() {
+	_ = dc.client.Count(dmContextCanceled, 1, dc.tags, 1.0)
+}
+
+// IncrementContextDeadlineExceeded increments the number of requests abandoned
+// because the caller's context deadline passed.
+func (dc *DatadogCollector) IncrementContextDeadlineExceeded() {
+	_ = dc.client.Count(dmContextDeadline, 1, dc.tags, 1.0)
+}
+
 // IncrementFallbackSuccesses increments the number of successes that occurred
 // during the execution of the fallback function.
 func (dc *DatadogCollector) IncrementFallbackSuccesses() {
@@ -179,6 +195,18 @@ func (dc *DatadogCollector) IncrementFallbackFailures() {
 	_ = dc.client.Count(dmFallbackFailures, 1, dc.tags, 1.0)
 }
 
+// IncrementFallbackRejections increments the number of fallbacks which were
+// rejected because too many were running.
+func (dc *DatadogCollector) IncrementFallbackRejections() {
+	_ = dc.client.Count(dmFallbackRejections, 1, dc.tags, 1.0)
+}
+
+// IncrementHedges increments the number of requests for which a second,
+// hedged attempt was started.
+func (dc *DatadogCollector) IncrementHedges() {
+	_ = dc.client.Count(dmHedges, 1, dc.tags, 1.0)
+}
+
 // UpdateTotalDuration updates the internal counter of how long we've run for.
 func (dc *DatadogCollector) UpdateTotalDuration(timeSinceStart time.Duration) {
 	ms := float64(timeSinceStart.Nanoseconds() / 1000000)
@@ -21,18 +21,22 @@ var makeCounterFunc = func() interface{} { return metrics.NewCounter() }
 // This Collector uses github.com/rcrowley/go-metrics for aggregation. See that repo for more details
 // on how metrics are aggregated and expressed in graphite.
 type GraphiteCollector struct {
-	attemptsPrefix          string
-	queueSizePrefix         string
-	errorsPrefix            string
-	successesPrefix         string
-	failuresPrefix          string
-	rejectsPrefix           string
-	shortCircuitsPrefix     string
-	timeoutsPrefix          string
-	fallbackSuccessesPrefix string
-	fallbackFailuresPrefix  string
-	totalDurationPrefix     string
-	runDurationPrefix       string
+	attemptsPrefix           string
+	queueSizePrefix          string
+	errorsPrefix             string
+	successesPrefix          string
+	failuresPrefix           string
+	rejectsPrefix            string
+	shortCircuitsPrefix      string
+	timeoutsPrefix           string
+	contextCanceledPrefix    string
+	contextDeadlinePrefix    string
+	fallbackSuccessesPrefix  string
+	fallbackFailuresPrefix   string
+	fallbackRejectionsPrefix string
+	hedgesPrefix             string
+	totalDurationPrefix      string
+	runDurationPrefix        string
 }
 
 // GraphiteCollectorConfig provides configuration that the graphite client will need.
@@ -59,18 +63,22 @@ func NewGraphiteCollector(name string, commandGroup string) metricCollector.Metr
 	name = strings.Replace(name, ":", "-", -1)
 	name = strings.Replace(name, ".", "-", -1)
 	return &GraphiteCollector{
-		attemptsPrefix:          commandGroup + "." + name + ".attempts",
-		errorsPrefix:            commandGroup + "." + name + ".errors",
-		queueSizePrefix:         commandGroup + "." + name + ".queueLength",
-		successesPrefix:         commandGroup + "." + name + ".successes",
-		failuresPrefix:          commandGroup + "." + name + ".failures",
-		rejectsPrefix:           commandGroup + "." + name + ".rejects",
-		shortCircuitsPrefix:     commandGroup + "." + name + ".shortCircuits",
-		timeoutsPrefix:          commandGroup + "." + name + ".timeouts",
-		fallbackSuccessesPrefix: commandGroup + "." + name + ".fallbackSuccesses",
-		fallbackFailuresPrefix:  commandGroup + "." + name + ".fallbackFailures",
-		totalDurationPrefix:     commandGroup + "." + name + ".totalDuration",
-		runDurationPrefix:       commandGroup + "." + name + ".runDuration",
+		attemptsPrefix:           commandGroup + "." + name + ".attempts",
+		errorsPrefix:             commandGroup + "." + name + ".errors",
+		queueSizePrefix:          commandGroup + "." + name + ".queueLength",
+		successesPrefix:          commandGroup + "." + name + ".successes",
+		failuresPrefix:           commandGroup + "." + name + ".failures",
+		rejectsPrefix:            commandGroup + "." + name + ".rejects",
+		shortCircuitsPrefix:      commandGroup + "." + name + ".shortCircuits",
+		timeoutsPrefix:           commandGroup + "." + name + ".timeouts",
+		contextCanceledPrefix:    commandGroup + "." + name + ".contextCanceled",
+		contextDeadlinePrefix:    commandGroup + "." + name + ".contextDeadlineExceeded",
+		fallbackSuccessesPrefix:  commandGroup + "." + name + ".fallbackSuccesses",
+		fallbackFailuresPrefix:   commandGroup + "." + name + ".fallbackFailures",
+		fallbackRejectionsPrefix: commandGroup + "." + name + ".fallbackRejections",
+		hedgesPrefix:             commandGroup + "." + name + ".hedges",
+		totalDurationPrefix:      commandGroup + "." + name + ".totalDuration",
+		runDurationPrefix:        commandGroup + "." + name + ".runDuration",
 	}
 }
 
@@ -142,6 +150,18 @@ func (g *GraphiteCollector) IncrementTimeouts() {
 	g.incrementCounterMetric(g.timeoutsPrefix)
 }
 
+// IncrementContextCanceled increments the number of requests abandoned because the caller's context was canceled.
+// This registers as a counter in the graphite collector.
+func (g *GraphiteCollector) IncrementContextCanceled() {
+	g.incrementCounterMetric(g.contextCanceledPrefix)
+}
+
+// IncrementContextDeadlineExceeded increments the number of requests abandoned because the caller's context deadline passed.
+// This registers as a counter in the graphite collector.
+func (g *GraphiteCollector) IncrementContextDeadlineExceeded() {
+	g.incrementCounterMetric(g.contextDeadlinePrefix)
+}
+
 // IncrementFallbackSuccesses increments the number of successes that occurred during the execution of the fallback function.
 // This registers as a counter in the graphite collector.
 func (g *GraphiteCollector) IncrementFallbackSuccesses() {
@@ -154,6 +174,18 @@ func (g *GraphiteCollector) IncrementFallbackFailures() {
 	g.incrementCounterMetric(g.fallbackFailuresPrefix)
 }
 
+// IncrementFallbackRejections increments the number of fallbacks which were rejected because too many were running.
+// This registers as a counter in the graphite collector.
+func (g *GraphiteCollector) IncrementFallbackRejections() {
+	g.incrementCounterMetric(g.fallbackRejectionsPrefix)
+}
+
+// IncrementHedges increments the number of requests for which a second, hedged attempt was started.
+// This registers as a counter in the graphite collector.
+func (g *GraphiteCollector) IncrementHedges() {
+	g.incrementCounterMetric(g.hedgesPrefix)
+}
+
 // UpdateTotalDuration updates the internal counter of how long we've run for.
 // This registers as a timer in the graphite collector.
 func (g *GraphiteCollector) UpdateTotalDuration(timeSinceStart time.Duration) {
@@ -0,0 +1,121 @@
+// Package otel reports hystrix command executions to OpenTelemetry.
+//
+// An Interceptor wraps every command in a span, and NewMetricCollector emits the metrics of
+// circuits as OpenTelemetry instruments:
+//
+//	hystrix.AddInterceptor(otel.NewInterceptor(otel.DefaultTracerProvider()))
+//
+//	collector, err := otel.NewMetricCollector(otel.DefaultMeterProvider().Meter("hystrix"))
+//	if err != nil {
+//		panic(err)
+//	}
+//	metricCollector.Registry.Register(collector)
+package otel
+
+import (
+	"context"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer and meter of this package.
+const instrumentationName = "github.com/myteksi/hystrix-go/plugins/otel"
+
+// Attribute keys recorded on spans, and on metrics for the circuit and group.
+const (
+	CircuitKey     = attribute.Key("hystrix.circuit")
+	GroupKey       = attribute.Key("hystrix.group")
+	OutcomeKey     = attribute.Key("hystrix.outcome")
+	QueueWaitKey   = attribute.Key("hystrix.queue_wait_ms")
+	RunDurationKey = attribute.Key("hystrix.run_duration_ms")
+)
+
+// DefaultTracerProvider returns the globally registered TracerProvider.
+func DefaultTracerProvider() trace.TracerProvider {
+	return otel.GetTracerProvider()
+}
+
+// DefaultMeterProvider returns the globally registered MeterProvider.
+func DefaultMeterProvider() metric.MeterProvider {
+	return otel.GetMeterProvider()
+}
+
+// Interceptor is a hystrix.Interceptor starting a span for each command execution.
+// The span covers the whole execution including queueing and fallback, and is the parent of
+// spans started by the run and fallback functions from the context passed to them.
+type Interceptor struct {
+	hystrix.NopInterceptor
+
+	tracer trace.Tracer
+}
+
+// NewInterceptor creates an Interceptor tracing commands with a tracer of provider.
+func NewInterceptor(provider trace.TracerProvider) *Interceptor {
+	return &Interceptor{
+		tracer: provider.Tracer(instrumentationName),
+	}
+}
+
+// BeforeAcquire starts the span of the execution.
+func (i *Interceptor) BeforeAcquire(ctx context.Context, exec *hystrix.Execution) context.Context {
+	ctx, _ = i.tracer.Start(ctx, exec.Name,
+		trace.WithTimestamp(exec.Start),
+		trace.WithAttributes(CircuitKey.String(exec.Name), GroupKey.String(exec.CommandGroup)),
+	)
+	return ctx
+}
+
+// AfterAcquire records the time spent waiting for an execution slot.
+func (i *Interceptor) AfterAcquire(ctx context.Context, exec *hystrix.Execution, wait time.Duration, err error) {
+	trace.SpanFromContext(ctx).SetAttributes(QueueWaitKey.Float64(milliseconds(wait)))
+}
+
+// AfterRun records the run duration and the error of the run function.
+func (i *Interceptor) AfterRun(ctx context.Context, exec *hystrix.Execution, duration time.Duration, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(RunDurationKey.Float64(milliseconds(duration)))
+	if err != nil {
+		span.RecordError(err)
+	}
+}
+
+// AfterFallback records the error of the fallback function.
+func (i *Interceptor) AfterFallback(ctx context.Context, exec *hystrix.Execution, err error) {
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+// Done records the outcome of the execution and ends its span.
+func (i *Interceptor) Done(ctx context.Context, exec *hystrix.Execution, result *hystrix.ExecutionResult) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(OutcomeKey.String(outcome(result)))
+	if result.Err != nil {
+		span.SetStatus(codes.Error, result.Err.Error())
+	}
+	span.End()
+}
+
+// outcome summarizes an execution: the result of the fallback when there was one,
+// otherwise the event which ended the execution, such as success, timeout or short-circuit.
+func outcome(result *hystrix.ExecutionResult) string {
+	if fallback := result.Fallback(); fallback != 0 {
+		return fallback.String()
+	}
+	for _, event := range result.Events {
+		if event != hystrix.EventQueued && event != hystrix.EventHedged {
+			return event.String()
+		}
+	}
+	return ""
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,185 @@
+package otel
+
+import (
+	"context"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// MetricCollector fulfills the metricCollector interface by recording the metrics of a circuit
+// in OpenTelemetry instruments, with the circuit and group as attributes.
+type MetricCollector struct {
+	instruments *instruments
+	attributes  metric.MeasurementOption
+}
+
+// instruments are shared by the collectors of all circuits.
+type instruments struct {
+	attempts                metric.Int64Counter
+	queueSize               metric.Int64Counter
+	errors                  metric.Int64Counter
+	successes               metric.Int64Counter
+	failures                metric.Int64Counter
+	rejects                 metric.Int64Counter
+	shortCircuits           metric.Int64Counter
+	timeouts                metric.Int64Counter
+	contextCanceled         metric.Int64Counter
+	contextDeadlineExceeded metric.Int64Counter
+	fallbackSuccesses       metric.Int64Counter
+	fallbackFailures        metric.Int64Counter
+	fallbackRejections      metric.Int64Counter
+	hedges                  metric.Int64Counter
+	totalDuration           metric.Float64Histogram
+	runDuration             metric.Float64Histogram
+}
+
+// NewMetricCollector creates the hystrix instruments with meter and returns an initializer of
+// collectors to register with a metricCollector.MetricCollectorRegistry.
+func NewMetricCollector(meter metric.Meter) (func(string, string) metricCollector.MetricCollector, error) {
+	var err error
+	counter := func(name string, description string) metric.Int64Counter {
+		if err != nil {
+			return nil
+		}
+		var c metric.Int64Counter
+		c, err = meter.Int64Counter(name, metric.WithDescription(description), metric.WithUnit("{command}"))
+		return c
+	}
+	histogram := func(name string, description string) metric.Float64Histogram {
+		if err != nil {
+			return nil
+		}
+		var h metric.Float64Histogram
+		h, err = meter.Float64Histogram(name, metric.WithDescription(description), metric.WithUnit("s"))
+		return h
+	}
+
+	i := &instruments{
+		attempts:                counter("hystrix.attempts", "Number of commands attempted."),
+		queueSize:               counter("hystrix.queued", "Number of commands queued for an execution slot."),
+		errors:                  counter("hystrix.errors", "Number of commands which did not succeed."),
+		successes:               counter("hystrix.successes", "Number of commands which succeeded."),
+		failures:                counter("hystrix.failures", "Number of commands which returned an error."),
+		rejects:                 counter("hystrix.rejects", "Number of commands rejected for lack of an execution slot."),
+		shortCircuits:           counter("hystrix.short_circuits", "Number of commands rejected by an open circuit."),
+		timeouts:                counter("hystrix.timeouts", "Number of commands which timed out."),
+		contextCanceled:         counter("hystrix.context_canceled", "Number of commands abandoned because their context was canceled."),
+		contextDeadlineExceeded: counter("hystrix.context_deadline_exceeded", "Number of commands abandoned because their context deadline passed."),
+		fallbackSuccesses:       counter("hystrix.fallback_successes", "Number of fallbacks which succeeded."),
+		fallbackFailures:        counter("hystrix.fallback_failures", "Number of fallbacks which failed."),
+		fallbackRejections:      counter("hystrix.fallback_rejections", "Number of fallbacks rejected because too many were running."),
+		hedges:                  counter("hystrix.hedges", "Number of commands for which a hedged attempt was started."),
+		totalDuration:           histogram("hystrix.total_duration", "Time from the start of commands to their result, including queueing and fallbacks."),
+		runDuration:             histogram("hystrix.run_duration", "Time spent running commands."),
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func(name string, commandGroup string) metricCollector.MetricCollector {
+		return &MetricCollector{
+			instruments: i,
+			attributes:  metric.WithAttributeSet(attribute.NewSet(CircuitKey.String(name), GroupKey.String(commandGroup))),
+		}
+	}, nil
+}
+
+func (mc *MetricCollector) add(counter metric.Int64Counter) {
+	counter.Add(context.Background(), 1, mc.attributes)
+}
+
+// IncrementAttempts increments the number of calls to this circuit.
+func (mc *MetricCollector) IncrementAttempts() {
+	mc.add(mc.instruments.attempts)
+}
+
+// IncrementQueueSize increments the number of elements in the queue.
+func (mc *MetricCollector) IncrementQueueSize() {
+	mc.add(mc.instruments.queueSize)
+}
+
+// IncrementErrors increments the number of unsuccessful attempts.
+// Attempts minus Errors will equal successes within a time range.
+// Errors are any result from an attempt that is not a success.
+func (mc *MetricCollector) IncrementErrors() {
+	mc.add(mc.instruments.errors)
+}
+
+// IncrementSuccesses increments the number of requests that succeed.
+func (mc *MetricCollector) IncrementSuccesses() {
+	mc.add(mc.instruments.successes)
+}
+
+// IncrementFailures increments the number of requests that fail.
+func (mc *MetricCollector) IncrementFailures() {
+	mc.add(mc.instruments.failures)
+}
+
+// IncrementRejects increments the number of requests that are rejected.
+func (mc *MetricCollector) IncrementRejects() {
+	mc.add(mc.instruments.rejects)
+}
+
+// IncrementShortCircuits increments the number of requests that short circuited
+// due to the circuit being open.
+func (mc *MetricCollector) IncrementShortCircuits() {
+	mc.add(mc.instruments.shortCircuits)
+}
+
+// IncrementTimeouts increments the number of timeouts that occurred in the
+// circuit breaker.
+func (mc *MetricCollector) IncrementTimeouts() {
+	mc.add(mc.instruments.timeouts)
+}
+
+// IncrementContextCanceled increments the number of requests abandoned because
+// the caller's context was canceled.
+func (mc *MetricCollector) IncrementContextCanceled() {
+	mc.add(mc.instruments.contextCanceled)
+}
+
+// IncrementContextDeadlineExceeded increments the number of requests abandoned
+// because the caller's context deadline passed.
+func (mc *MetricCollector) IncrementContextDeadlineExceeded() {
+	mc.add(mc.instruments.contextDeadlineExceeded)
+}
+
+// IncrementFallbackSuccesses increments the number of successes that occurred
+// during the execution of the fallback function.
+func (mc *MetricCollector) IncrementFallbackSuccesses() {
+	mc.add(mc.instruments.fallbackSuccesses)
+}
+
+// IncrementFallbackFailures increments the number of failures that occurred
+// during the execution of the fallback function.
+func (mc *MetricCollector) IncrementFallbackFailures() {
+	mc.add(mc.instruments.fallbackFailures)
+}
+
+// IncrementFallbackRejections increments the number of fallbacks which were
+// rejected because too many were running.
+func (mc *MetricCollector) IncrementFallbackRejections() {
+	mc.add(mc.instruments.fallbackRejections)
+}
+
+// IncrementHedges increments the number of requests for which a second,
+// hedged attempt was started.
+func (mc *MetricCollector) IncrementHedges() {
+	mc.add(mc.instruments.hedges)
+}
+
+// UpdateTotalDuration updates the internal counter of how long we've run for.
+func (mc *MetricCollector) UpdateTotalDuration(timeSinceStart time.Duration) {
+	mc.instruments.totalDuration.Record(context.Background(), timeSinceStart.Seconds(), mc.attributes)
+}
+
+// UpdateRunDuration updates the internal counter of how long the last run took.
+func (mc *MetricCollector) UpdateRunDuration(runDuration time.Duration) {
+	mc.instruments.runDuration.Record(context.Background(), runDuration.Seconds(), mc.attributes)
+}
+
+// Reset is a noop operation in this collector, OpenTelemetry counters only go up.
+func (mc *MetricCollector) Reset() {}
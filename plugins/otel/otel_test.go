@@ -0,0 +1,120 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func waitForSpans(recorder *tracetest.SpanRecorder, n int) []sdktrace.ReadOnlySpan {
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Ended()) < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return recorder.Ended()
+}
+
+func TestInterceptor(t *testing.T) {
+	Convey("given a registry traced by an interceptor", t, func() {
+		recorder := tracetest.NewSpanRecorder()
+		provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+		registry := hystrix.NewRegistry()
+		registry.ConfigureCommand("traced", hystrix.CommandConfig{CommandGroup: "group"})
+		registry.AddInterceptor(NewInterceptor(provider))
+
+		Convey("a successful command is recorded as a span", func() {
+			var parent trace.SpanContext
+			err := registry.DoC(context.Background(), "traced", func(ctx context.Context) error {
+				parent = trace.SpanContextFromContext(ctx)
+				return nil
+			}, nil)
+			So(err, ShouldBeNil)
+
+			spans := waitForSpans(recorder, 1)
+			So(spans, ShouldHaveLength, 1)
+			So(spans[0].Name(), ShouldEqual, "traced")
+			So(spanAttribute(spans[0], CircuitKey).AsString(), ShouldEqual, "traced")
+			So(spanAttribute(spans[0], GroupKey).AsString(), ShouldEqual, "group")
+			So(spanAttribute(spans[0], OutcomeKey).AsString(), ShouldEqual, "success")
+			So(spanAttribute(spans[0], RunDurationKey).Type(), ShouldEqual, attribute.FLOAT64)
+
+			Convey("which is the parent of spans started by run", func() {
+				So(parent.SpanID(), ShouldEqual, spans[0].SpanContext().SpanID())
+			})
+		})
+
+		Convey("a command served by its fallback records the outcome of the fallback", func() {
+			err := registry.Do("traced", func() error {
+				return errors.New("run failed")
+			}, func(err error) error {
+				return nil
+			})
+			So(err, ShouldBeNil)
+
+			spans := waitForSpans(recorder, 1)
+			So(spans, ShouldHaveLength, 1)
+			So(spanAttribute(spans[0], OutcomeKey).AsString(), ShouldEqual, "fallback-success")
+		})
+	})
+}
+
+func TestOutcome(t *testing.T) {
+	Convey("the outcome of an execution", t, func() {
+		So(outcome(&hystrix.ExecutionResult{Events: []hystrix.EventType{hystrix.EventSuccess}}), ShouldEqual, "success")
+		So(outcome(&hystrix.ExecutionResult{Events: []hystrix.EventType{hystrix.EventQueued, hystrix.EventTimeout}}), ShouldEqual, "timeout")
+		So(outcome(&hystrix.ExecutionResult{Events: []hystrix.EventType{hystrix.EventShortCircuit, hystrix.EventFallbackFailure}}), ShouldEqual, "fallback-failure")
+	})
+}
+
+func TestMetricCollector(t *testing.T) {
+	Convey("given a registry collecting OpenTelemetry metrics", t, func() {
+		reader := sdkmetric.NewManualReader()
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+		collector, err := NewMetricCollector(provider.Meter("test"))
+		So(err, ShouldBeNil)
+
+		registry := hystrix.NewRegistry()
+		registry.MetricCollectors().Register(collector)
+
+		Convey("after running a command", func() {
+			So(registry.Do("measured", func() error { return nil }, nil), ShouldBeNil)
+			// metrics are reported asynchronously
+			time.Sleep(100 * time.Millisecond)
+
+			var data metricdata.ResourceMetrics
+			So(reader.Collect(context.Background(), &data), ShouldBeNil)
+
+			names := map[string]bool{}
+			for _, scope := range data.ScopeMetrics {
+				for _, m := range scope.Metrics {
+					names[m.Name] = true
+				}
+			}
+
+			Convey("its events and durations are recorded", func() {
+				So(names["hystrix.attempts"], ShouldBeTrue)
+				So(names["hystrix.successes"], ShouldBeTrue)
+				So(names["hystrix.run_duration"], ShouldBeTrue)
+			})
+		})
+	})
+}
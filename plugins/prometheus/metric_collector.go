@@ -0,0 +1,333 @@
+// Package prometheus exposes the metrics of hystrix circuits to a Prometheus server.
+//
+// NewMetricCollector registers the metrics and returns an initializer of collectors, which is
+// registered with hystrix like any other metric collector.
+package prometheus
+
+import (
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Every metric of the MetricCollector carries these labels.
+var labelNames = []string{"circuit", "group"}
+
+// MetricCollector fulfills the metricCollector interface allowing users to
+// expose circuit stats to a Prometheus server.
+//
+// Every event is counted in its own counter and durations are observed in histograms,
+// all labelled by circuit and command group, e.g. hystrix_attempts_total{circuit="my_command",group="my_group"}.
+type MetricCollector struct {
+	attempts                prometheus.Counter
+	queueSize               prometheus.Counter
+	errors                  prometheus.Counter
+	successes               prometheus.Counter
+	failures                prometheus.Counter
+	rejects                 prometheus.Counter
+	shortCircuits           prometheus.Counter
+	timeouts                prometheus.Counter
+	contextCanceled         prometheus.Counter
+	contextDeadlineExceeded prometheus.Counter
+	fallbackSuccesses       prometheus.Counter
+	fallbackFailures        prometheus.Counter
+	fallbackRejections      prometheus.Counter
+	hedges                  prometheus.Counter
+	totalDuration           prometheus.Observer
+	runDuration             prometheus.Observer
+
+	vectors *vectors
+	// labels are the label values of the series of the circuit
+	labels []string
+}
+
+// vectors holds the metric vectors shared by the collectors of all circuits.
+type vectors struct {
+	attempts                *prometheus.CounterVec
+	queueSize               *prometheus.CounterVec
+	errors                  *prometheus.CounterVec
+	successes               *prometheus.CounterVec
+	failures                *prometheus.CounterVec
+	rejects                 *prometheus.CounterVec
+	shortCircuits           *prometheus.CounterVec
+	timeouts                *prometheus.CounterVec
+	contextCanceled         *prometheus.CounterVec
+	contextDeadlineExceeded *prometheus.CounterVec
+	fallbackSuccesses       *prometheus.CounterVec
+	fallbackFailures        *prometheus.CounterVec
+	fallbackRejections      *prometheus.CounterVec
+	hedges                  *prometheus.CounterVec
+	totalDuration           *prometheus.HistogramVec
+	runDuration             *prometheus.HistogramVec
+}
+
+// NewMetricCollector registers the hystrix metrics with registerer and returns
+// an initializer of collectors for the circuits of the default hystrix registry.
+//
+// namespace is prepended to every metric name and may be an empty string.
+//
+// Example use
+//
+//	package main
+//
+//	import (
+//		"net/http"
+//
+//		"github.com/myteksi/hystrix-go/hystrix/metric_collector"
+//		hystrixprometheus "github.com/myteksi/hystrix-go/plugins/prometheus"
+//		"github.com/prometheus/client_golang/prometheus"
+//		"github.com/prometheus/client_golang/prometheus/promhttp"
+//	)
+//
+//	func main() {
+//		collector, err := hystrixprometheus.NewMetricCollector(prometheus.DefaultRegisterer, "hystrix")
+//		if err != nil {
+//			panic(err)
+//		}
+//		metricCollector.Registry.Register(collector)
+//
+//		http.Handle("/metrics", promhttp.Handler())
+//		http.ListenAndServe(":8080", nil)
+//	}
+func NewMetricCollector(registerer prometheus.Registerer, namespace string) (func(string, string) metricCollector.MetricCollector, error) {
+	return NewMetricCollectorForRegistry(hystrix.DefaultRegistry(), registerer, namespace)
+}
+
+// NewMetricCollectorForRegistry is like NewMetricCollector for the circuits of the given hystrix registry.
+// The returned initializer has to be registered with the MetricCollectors of that registry.
+//
+// Besides the metrics recorded by the collectors, the open state, active count and queue depth
+// of every circuit, keyed circuits included, are read from the registry on each scrape.
+func NewMetricCollectorForRegistry(circuits *hystrix.Registry, registerer prometheus.Registerer, namespace string) (func(string, string) metricCollector.MetricCollector, error) {
+	counter := func(name string, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, labelNames)
+	}
+	histogram := func(name string, help string) *prometheus.HistogramVec {
+		return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+			Buckets:   prometheus.DefBuckets,
+		}, labelNames)
+	}
+
+	m := &vectors{
+		attempts:                counter("attempts_total", "Number of commands attempted."),
+		queueSize:               counter("queued_total", "Number of commands queued for an execution slot."),
+		errors:                  counter("errors_total", "Number of commands which did not succeed."),
+		successes:               counter("successes_total", "Number of commands which succeeded."),
+		failures:                counter("failures_total", "Number of commands which returned an error."),
+		rejects:                 counter("rejects_total", "Number of commands rejected for lack of an execution slot."),
+		shortCircuits:           counter("short_circuits_total", "Number of commands rejected by an open circuit."),
+		timeouts:                counter("timeouts_total", "Number of commands which timed out."),
+		contextCanceled:         counter("context_canceled_total", "Number of commands abandoned because their context was canceled."),
+		contextDeadlineExceeded: counter("context_deadline_exceeded_total", "Number of commands abandoned because their context deadline passed."),
+		fallbackSuccesses:       counter("fallback_successes_total", "Number of fallbacks which succeeded."),
+		fallbackFailures:        counter("fallback_failures_total", "Number of fallbacks which failed."),
+		fallbackRejections:      counter("fallback_rejections_total", "Number of fallbacks rejected because too many were running."),
+		hedges:                  counter("hedges_total", "Number of commands for which a hedged attempt was started."),
+		totalDuration:           histogram("total_duration_seconds", "Time from the start of commands to their result, including queueing and fallbacks."),
+		runDuration:             histogram("run_duration_seconds", "Time spent running commands."),
+	}
+
+	for _, c := range []prometheus.Collector{
+		m.attempts, m.queueSize, m.errors, m.successes, m.failures, m.rejects, m.shortCircuits, m.timeouts,
+		m.contextCanceled, m.contextDeadlineExceeded, m.fallbackSuccesses, m.fallbackFailures, m.fallbackRejections,
+		m.hedges,
+		m.totalDuration, m.runDuration,
+		newCircuitCollector(circuits, namespace),
+	} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.newMetricCollector, nil
+}
+
+// vecs returns the metric vectors of m, whose series are deleted by label values.
+func (m *vectors) vecs() []interface{ DeleteLabelValues(...string) bool } {
+	return []interface{ DeleteLabelValues(...string) bool }{
+		m.attempts, m.queueSize, m.errors, m.successes, m.failures, m.rejects, m.shortCircuits, m.timeouts,
+		m.contextCanceled, m.contextDeadlineExceeded, m.fallbackSuccesses, m.fallbackFailures, m.fallbackRejections,
+		m.hedges,
+		m.totalDuration, m.runDuration,
+	}
+}
+
+func (m *vectors) newMetricCollector(name string, commandGroup string) metricCollector.MetricCollector {
+	return &MetricCollector{
+		attempts:                m.attempts.WithLabelValues(name, commandGroup),
+		queueSize:               m.queueSize.WithLabelValues(name, commandGroup),
+		errors:                  m.errors.WithLabelValues(name, commandGroup),
+		successes:               m.successes.WithLabelValues(name, commandGroup),
+		failures:                m.failures.WithLabelValues(name, commandGroup),
+		rejects:                 m.rejects.WithLabelValues(name, commandGroup),
+		shortCircuits:           m.shortCircuits.WithLabelValues(name, commandGroup),
+		timeouts:                m.timeouts.WithLabelValues(name, commandGroup),
+		contextCanceled:         m.contextCanceled.WithLabelValues(name, commandGroup),
+		contextDeadlineExceeded: m.contextDeadlineExceeded.WithLabelValues(name, commandGroup),
+		fallbackSuccesses:       m.fallbackSuccesses.WithLabelValues(name, commandGroup),
+		fallbackFailures:        m.fallbackFailures.WithLabelValues(name, commandGroup),
+		fallbackRejections:      m.fallbackRejections.WithLabelValues(name, commandGroup),
+		hedges:                  m.hedges.WithLabelValues(name, commandGroup),
+		totalDuration:           m.totalDuration.WithLabelValues(name, commandGroup),
+		runDuration:             m.runDuration.WithLabelValues(name, commandGroup),
+		vectors:                 m,
+		labels:                  []string{name, commandGroup},
+	}
+}
+
+// IncrementAttempts increments the number of calls to this circuit.
+func (pc *MetricCollector) IncrementAttempts() {
+	pc.attempts.Inc()
+}
+
+// IncrementQueueSize increments the number of elements in the queue.
+func (pc *MetricCollector) IncrementQueueSize() {
+	pc.queueSize.Inc()
+}
+
+// IncrementErrors increments the number of unsuccessful attempts.
+// Attempts minus Errors will equal successes within a time range.
+// Errors are any result from an attempt that is not a success.
+func (pc *MetricCollector) IncrementErrors() {
+	pc.errors.Inc()
+}
+
+// IncrementSuccesses increments the number of requests that succeed.
+func (pc *MetricCollector) IncrementSuccesses() {
+	pc.successes.Inc()
+}
+
+// IncrementFailures increments the number of requests that fail.
+func (pc *MetricCollector) IncrementFailures() {
+	pc.failures.Inc()
+}
+
+// IncrementRejects increments the number of requests that are rejected.
+func (pc *MetricCollector) IncrementRejects() {
+	pc.rejects.Inc()
+}
+
+// IncrementShortCircuits increments the number of requests that short circuited
+// due to the circuit being open.
+func (pc *MetricCollector) IncrementShortCircuits() {
+	pc.shortCircuits.Inc()
+}
+
+// IncrementTimeouts increments the number of timeouts that occurred in the
+// circuit breaker.
+func (pc *MetricCollector) IncrementTimeouts() {
+	pc.timeouts.Inc()
+}
+
+// IncrementContextCanceled increments the number of requests abandoned because
+// the caller's context was canceled.
+func (pc *MetricCollector) IncrementContextCanceled() {
+	pc.contextCanceled.Inc()
+}
+
+// IncrementContextDeadlineExceeded increments the number of requests abandoned
+// because the caller's context deadline passed.
+func (pc *MetricCollector) IncrementContextDeadlineExceeded() {
+	pc.contextDeadlineExceeded.Inc()
+}
+
+// IncrementFallbackSuccesses increments the number of successes that occurred
+// during the execution of the fallback function.
+func (pc *MetricCollector) IncrementFallbackSuccesses() {
+	pc.fallbackSuccesses.Inc()
+}
+
+// IncrementFallbackFailures increments the number of failures that occurred
+// during the execution of the fallback function.
+func (pc *MetricCollector) IncrementFallbackFailures() {
+	pc.fallbackFailures.Inc()
+}
+
+// IncrementFallbackRejections increments the number of fallbacks which were
+// rejected because too many were running.
+func (pc *MetricCollector) IncrementFallbackRejections() {
+	pc.fallbackRejections.Inc()
+}
+
+// IncrementHedges increments the number of requests for which a second,
+// hedged attempt was started.
+func (pc *MetricCollector) IncrementHedges() {
+	pc.hedges.Inc()
+}
+
+// UpdateTotalDuration updates the internal counter of how long we've run for.
+func (pc *MetricCollector) UpdateTotalDuration(timeSinceStart time.Duration) {
+	pc.totalDuration.Observe(timeSinceStart.Seconds())
+}
+
+// UpdateRunDuration updates the internal counter of how long the last run took.
+func (pc *MetricCollector) UpdateRunDuration(runDuration time.Duration) {
+	pc.runDuration.Observe(runDuration.Seconds())
+}
+
+// Reset is a noop operation in this collector, Prometheus counters only go up.
+func (pc *MetricCollector) Reset() {}
+
+// Release deletes the series of the circuit, whose circuit was discarded, e.g. the circuit of an evicted key.
+func (pc *MetricCollector) Release() {
+	for _, vec := range pc.vectors.vecs() {
+		vec.DeleteLabelValues(pc.labels...)
+	}
+}
+
+// circuitCollector reports the state of the circuits of a registry when it is scraped.
+type circuitCollector struct {
+	circuits *hystrix.Registry
+	open     *prometheus.Desc
+	active   *prometheus.Desc
+	waiting  *prometheus.Desc
+	limit    *prometheus.Desc
+}
+
+func newCircuitCollector(circuits *hystrix.Registry, namespace string) *circuitCollector {
+	return &circuitCollector{
+		circuits: circuits,
+		open:     prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "circuit_open"), "Whether the circuit is open, 1 when open.", labelNames, nil),
+		active:   prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "active_count"), "Number of commands executing.", labelNames, nil),
+		waiting:  prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "queue_depth"), "Number of commands queued for an execution slot.", labelNames, nil),
+		limit:    prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "concurrency_limit"), "Number of commands allowed to execute at the same time.", labelNames, nil),
+	}
+}
+
+func (c *circuitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.open
+	ch <- c.active
+	ch <- c.waiting
+	ch <- c.limit
+}
+
+func (c *circuitCollector) Collect(ch chan<- prometheus.Metric) {
+	circuits := c.circuits.Circuits()
+	// keyed circuits exist for commands with settings only
+	for command := range c.circuits.GetCircuitSettings() {
+		for _, cb := range c.circuits.KeyedCircuits(command) {
+			circuits[cb.Name] = cb
+		}
+	}
+
+	for name, cb := range circuits {
+		open := 0.0
+		if cb.IsOpen() {
+			open = 1
+		}
+
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, open, name, cb.CommandGroup)
+		ch <- prometheus.MustNewConstMetric(c.active, prometheus.GaugeValue, float64(cb.ActiveCount()), name, cb.CommandGroup)
+		ch <- prometheus.MustNewConstMetric(c.waiting, prometheus.GaugeValue, float64(cb.WaitingCount()), name, cb.CommandGroup)
+		ch <- prometheus.MustNewConstMetric(c.limit, prometheus.GaugeValue, float64(cb.ConcurrencyLimit()), name, cb.CommandGroup)
+	}
+}
@@ -0,0 +1,83 @@
+package prometheus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/myteksi/hystrix-go/hystrix"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMetricCollector(t *testing.T) {
+	Convey("given a prometheus collector registered with a hystrix registry", t, func() {
+		promRegistry := prometheus.NewRegistry()
+		registry := hystrix.NewRegistry()
+		registry.ConfigureCommand("prom", hystrix.CommandConfig{CommandGroup: "group"})
+
+		collector, err := NewMetricCollectorForRegistry(registry, promRegistry, "hystrix")
+		So(err, ShouldBeNil)
+		registry.MetricCollectors().Register(collector)
+
+		Convey("registering it twice fails", func() {
+			_, err := NewMetricCollectorForRegistry(registry, promRegistry, "hystrix")
+			So(err, ShouldNotBeNil)
+		})
+
+		Convey("after running commands", func() {
+			So(registry.Do("prom", func() error { return nil }, nil), ShouldBeNil)
+			So(registry.Do("prom", func() error { return errors.New("failed") }, nil), ShouldNotBeNil)
+
+			// metrics are reported asynchronously
+			time.Sleep(100 * time.Millisecond)
+
+			Convey("events are counted per circuit and group", func() {
+				So(testutil.ToFloat64(collector("prom", "group").(*MetricCollector).attempts), ShouldEqual, 2)
+				So(testutil.ToFloat64(collector("prom", "group").(*MetricCollector).successes), ShouldEqual, 1)
+				So(testutil.ToFloat64(collector("prom", "group").(*MetricCollector).failures), ShouldEqual, 1)
+			})
+
+			Convey("durations are observed", func() {
+				count, err := testutil.GatherAndCount(promRegistry, "hystrix_run_duration_seconds")
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 1)
+			})
+
+			Convey("the circuit state is read on scrape", func() {
+				count, err := testutil.GatherAndCount(promRegistry, "hystrix_circuit_open", "hystrix_active_count", "hystrix_queue_depth", "hystrix_concurrency_limit")
+				So(err, ShouldBeNil)
+				So(count, ShouldEqual, 4)
+			})
+		})
+
+		Convey("the series of an evicted keyed circuit are deleted", func() {
+			registry.ConfigureCommand("prom", hystrix.CommandConfig{CommandGroup: "group", MaxKeys: 1})
+			So(registry.DoKeyed("prom", "host-a", func() error { return nil }, nil), ShouldBeNil)
+			time.Sleep(100 * time.Millisecond)
+			count, err := testutil.GatherAndCount(promRegistry, "hystrix_attempts_total")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+
+			So(registry.DoKeyed("prom", "host-b", func() error { return nil }, nil), ShouldBeNil)
+			time.Sleep(100 * time.Millisecond)
+			count, err = testutil.GatherAndCount(promRegistry, "hystrix_attempts_total")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+			So(testutil.ToFloat64(collector("prom:host-b", "group").(*MetricCollector).attempts), ShouldEqual, 1)
+		})
+
+		Convey("the state of keyed circuits is read on scrape", func() {
+			So(registry.DoKeyed("prom", "host-a", func() error { return nil }, nil), ShouldBeNil)
+			count, err := testutil.GatherAndCount(promRegistry, "hystrix_circuit_open")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 1)
+
+			So(registry.Do("prom", func() error { return nil }, nil), ShouldBeNil)
+			count, err = testutil.GatherAndCount(promRegistry, "hystrix_circuit_open")
+			So(err, ShouldBeNil)
+			So(count, ShouldEqual, 2)
+		})
+	})
+}
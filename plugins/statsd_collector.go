@@ -15,21 +15,25 @@ import (
 //
 // This Collector uses https://github.com/cactus/go-statsd-client/ for transport.
 type StatsdCollector struct {
-	client                  statsd.Statter
-	circuitOpenPrefix       string
-	attemptsPrefix          string
-	queueSizePrefix         string
-	errorsPrefix            string
-	successesPrefix         string
-	failuresPrefix          string
-	rejectsPrefix           string
-	shortCircuitsPrefix     string
-	timeoutsPrefix          string
-	fallbackSuccessesPrefix string
-	fallbackFailuresPrefix  string
-	totalDurationPrefix     string
-	runDurationPrefix       string
-	sampleRate              float32
+	client                   statsd.Statter
+	circuitOpenPrefix        string
+	attemptsPrefix           string
+	queueSizePrefix          string
+	errorsPrefix             string
+	successesPrefix          string
+	failuresPrefix           string
+	rejectsPrefix            string
+	shortCircuitsPrefix      string
+	timeoutsPrefix           string
+	contextCanceledPrefix    string
+	contextDeadlinePrefix    string
+	fallbackSuccessesPrefix  string
+	fallbackFailuresPrefix   string
+	fallbackRejectionsPrefix string
+	hedgesPrefix             string
+	totalDurationPrefix      string
+	runDurationPrefix        string
+	sampleRate               float32
 }
 
 type StatsdCollectorClient struct {
@@ -93,21 +97,25 @@ func (s *StatsdCollectorClient) NewStatsdCollector(name string, commandGroup str
 	commandGroup = formatStatsdString(commandGroup)
 
 	return &StatsdCollector{
-		client:                  s.client,
-		circuitOpenPrefix:       commandGroup + "." + name + ".circuitOpen",
-		attemptsPrefix:          commandGroup + "." + name + ".attempts",
-		errorsPrefix:            commandGroup + "." + name + ".errors",
-		queueSizePrefix:         commandGroup + "." + name + ".queueLength",
-		successesPrefix:         commandGroup + "." + name + ".successes",
-		failuresPrefix:          commandGroup + "." + name + ".failures",
-		rejectsPrefix:           commandGroup + "." + name + ".rejects",
-		shortCircuitsPrefix:     commandGroup + "." + name + ".shortCircuits",
-		timeoutsPrefix:          commandGroup + "." + name + ".timeouts",
-		fallbackSuccessesPrefix: commandGroup + "." + name + ".fallbackSuccesses",
-		fallbackFailuresPrefix:  commandGroup + "." + name + ".fallbackFailures",
-		totalDurationPrefix:     commandGroup + "." + name + ".totalDuration",
-		runDurationPrefix:       commandGroup + "." + name + ".runDuration",
-		sampleRate:              s.sampleRate,
+		client:                   s.client,
+		circuitOpenPrefix:        commandGroup + "." + name + ".circuitOpen",
+		attemptsPrefix:           commandGroup + "." + name + ".attempts",
+		errorsPrefix:             commandGroup + "." + name + ".errors",
+		queueSizePrefix:          commandGroup + "." + name + ".queueLength",
+		successesPrefix:          commandGroup + "." + name + ".successes",
+		failuresPrefix:           commandGroup + "." + name + ".failures",
+		rejectsPrefix:            commandGroup + "." + name + ".rejects",
+		shortCircuitsPrefix:      commandGroup + "." + name + ".shortCircuits",
+		timeoutsPrefix:           commandGroup + "." + name + ".timeouts",
+		contextCanceledPrefix:    commandGroup + "." + name + ".contextCanceled",
+		contextDeadlinePrefix:    commandGroup + "." + name + ".contextDeadlineExceeded",
+		fallbackSuccessesPrefix:  commandGroup + "." + name + ".fallbackSuccesses",
+		fallbackFailuresPrefix:   commandGroup + "." + name + ".fallbackFailures",
+		fallbackRejectionsPrefix: commandGroup + "." + name + ".fallbackRejections",
+		hedgesPrefix:             commandGroup + "." + name + ".hedges",
+		totalDurationPrefix:      commandGroup + "." + name + ".totalDuration",
+		runDurationPrefix:        commandGroup + "." + name + ".runDuration",
+		sampleRate:               s.sampleRate,
 	}
 }
 
@@ -193,6 +201,18 @@ func (g *StatsdCollector) IncrementTimeouts() {
 	g.incrementCounterMetric(g.timeoutsPrefix)
 }
 
+// IncrementContextCanceled increments the number of requests abandoned because the caller's context was canceled.
+// This registers as a counter in the Statsd collector.
+func (g *StatsdCollector) IncrementContextCanceled() {
+	g.incrementCounterMetric(g.contextCanceledPrefix)
+}
+
+// IncrementContextDeadlineExceeded increments the number of requests abandoned because the caller's context deadline passed.
+// This registers as a counter in the Statsd collector.
+func (g *StatsdCollector) IncrementContextDeadlineExceeded() {
+	g.incrementCounterMetric(g.contextDeadlinePrefix)
+}
+
 // IncrementFallbackSuccesses increments the number of successes that occurred during the execution of the fallback function.
 // This registers as a counter in the Statsd collector.
 func (g *StatsdCollector) IncrementFallbackSuccesses() {
@@ -205,6 +225,18 @@ func (g *StatsdCollector) IncrementFallbackFailures() {
 	g.incrementCounterMetric(g.fallbackFailuresPrefix)
 }
 
+// IncrementFallbackRejections increments the number of fallbacks which were rejected because too many were running.
+// This registers as a counter in the Statsd collector.
+func (g *StatsdCollector) IncrementFallbackRejections() {
+	g.incrementCounterMetric(g.fallbackRejectionsPrefix)
+}
+
+// IncrementHedges increments the number of requests for which a second, hedged attempt was started.
+// This registers as a counter in the Statsd collector.
+func (g *StatsdCollector) IncrementHedges() {
+	g.incrementCounterMetric(g.hedgesPrefix)
+}
+
 // UpdateTotalDuration updates the internal counter of how long we've run for.
 // This registers as a timer in the Statsd collector.
 func (g *StatsdCollector) UpdateTotalDuration(timeSinceStart time.Duration) {